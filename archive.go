@@ -0,0 +1,58 @@
+package calltimer
+
+import "maps"
+
+/*
+Archive stores a deep copy of all timers under a label, so that the current state can be retrieved later using Archives(). This is an in-memory history for long-running processes, e.g.:
+
+	calltimer.Archive("09:00")
+	...
+	calltimer.Archive("10:00")
+
+An archive is a Timer that is named after the label, has no activity of its own, and holds copies of all root timers as its children. Archiving under an existing label replaces the earlier archive.
+*/
+func Archive(label string) {
+	defaultRegistry.Archive(label)
+}
+
+/*
+Archive is like the package-level Archive(), but archives the timers of this registry. The archive is kept in this registry, see Registry.Archives().
+*/
+func (r *Registry) Archive(label string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	a := &Timer{Name: label, Children: []*Timer{}}
+	for _, root := range r.roots {
+		a.Children = append(a.Children, root.clone(a))
+	}
+	r.archives[label] = a
+}
+
+/*
+Archives returns the archives that were stored using Archive(), keyed by label.
+*/
+func Archives() map[string]*Timer {
+	return defaultRegistry.Archives()
+}
+
+/*
+Archives is like the package-level Archives(), but returns the archives of this registry.
+*/
+func (r *Registry) Archives() map[string]*Timer {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	return maps.Clone(r.archives)
+}
+
+// clone returns a deep copy of the timer and its children, attached to parent.
+func (t *Timer) clone(parent *Timer) *Timer {
+	c := t.copyStats()
+	c.Parent = parent
+	c.Children = make([]*Timer, 0, len(t.Children))
+	for _, child := range t.Children {
+		c.Children = append(c.Children, child.clone(c))
+	}
+	return c
+}
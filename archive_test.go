@@ -0,0 +1,33 @@
+package calltimer
+
+import (
+	"testing"
+	"time"
+)
+
+func TestArchive(t *testing.T) {
+	resetGlobals()
+
+	r := MustNew("root", nil)
+	c := MustNew("child", r)
+	c.LogDuration(time.Second)
+
+	Archive("first")
+	c.LogDuration(time.Second)
+	Archive("second")
+
+	got := Archives()
+	if len(got) != 2 {
+		t.Fatalf("Archives() has %v entries, want 2", len(got))
+	}
+	for label, want := range map[string]time.Duration{"first": time.Second, "second": 2 * time.Second} {
+		a := got[label]
+		if a.Name != label || len(a.Children) != 1 {
+			t.Fatalf("archive %q = %+v, want one root", label, a)
+		}
+		ac := a.Children[0].Children[0]
+		if ac == c || ac.TotalElapsed != want || ac.Parent != a.Children[0] {
+			t.Errorf("archive %q: child = %+v, want a copy with total %v", label, ac, want)
+		}
+	}
+}
@@ -0,0 +1,80 @@
+package calltimer
+
+import (
+	"bytes"
+	"runtime"
+	"strconv"
+	"sync"
+)
+
+var (
+	autoStacks = map[uint64][]*Timer{} // Per goroutine, the timers that StartAuto() started and that aren't stopped
+	autoMu     sync.Mutex              // Lock for autoStacks
+)
+
+/*
+StartAuto starts timing under a timer with the passed-in name, and returns a function that stops timing. The parent is inferred: when another StartAuto is running on the same goroutine, its timer becomes the parent, otherwise the timer is a root timer. A timer is created on first use and reused afterwards. The typical usage is:
+
+	func outer() {
+		defer calltimer.StartAuto("outer")()
+		inner()
+	}
+
+	func inner() {
+		defer calltimer.StartAuto("inner")() // becomes a child of "outer"
+		...
+	}
+
+The inference is goroutine-local: a StartAuto in a new goroutine doesn't see the timers of the goroutine that started it, and creates a root timer instead. The returned function must always be called, and in reverse order of the StartAuto calls, otherwise later inferences are wrong. A timer keeps the parent that it was created under; when the same name is started under a different parent, the timer is still used, but the report shows it under its original parent.
+
+StartAuto panics when the timer can't be created, like MustNew.
+*/
+func StartAuto(name string) func() {
+	if !Active {
+		return func() {}
+	}
+	gid := goroutineID()
+
+	autoMu.Lock()
+	stack := autoStacks[gid]
+	var parent *Timer
+	if len(stack) > 0 {
+		parent = stack[len(stack)-1]
+	}
+	autoMu.Unlock()
+
+	t := defaultRegistry.mustReuse(name, parent)
+
+	autoMu.Lock()
+	autoStacks[gid] = append(autoStacks[gid], t)
+	autoMu.Unlock()
+
+	stop := t.Start()
+	return func() {
+		stop()
+
+		autoMu.Lock()
+		defer autoMu.Unlock()
+		stack := autoStacks[gid]
+		if len(stack) > 0 {
+			stack = stack[:len(stack)-1]
+		}
+		if len(stack) == 0 {
+			delete(autoStacks, gid)
+		} else {
+			autoStacks[gid] = stack
+		}
+	}
+}
+
+// goroutineID returns the ID of the current goroutine, which is parsed from the header of its stack trace, e.g. "goroutine 18 [running]:".
+func goroutineID() uint64 {
+	var buf [64]byte
+	b := buf[:runtime.Stack(buf[:], false)]
+	b = bytes.TrimPrefix(b, []byte("goroutine "))
+	if i := bytes.IndexByte(b, ' '); i >= 0 {
+		b = b[:i]
+	}
+	id, _ := strconv.ParseUint(string(b), 10, 64)
+	return id
+}
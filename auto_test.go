@@ -0,0 +1,42 @@
+package calltimer
+
+import (
+	"sync"
+	"testing"
+)
+
+func TestStartAuto(t *testing.T) {
+	resetGlobals()
+
+	inner := func() {
+		defer StartAuto("auto-inner")()
+	}
+	outer := func() {
+		defer StartAuto("auto-outer")()
+		inner()
+		inner()
+	}
+	outer()
+
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		defer StartAuto("auto-other")()
+	}()
+	wg.Wait()
+
+	o, i, other := defaultRegistry.timers["auto-outer"], defaultRegistry.timers["auto-inner"], defaultRegistry.timers["auto-other"]
+	if o == nil || o.Parent != nil || o.CalledTimes != 1 {
+		t.Errorf("auto-outer = %+v, want a root timer with 1 call", o)
+	}
+	if i == nil || i.Parent != o || i.CalledTimes != 2 {
+		t.Errorf("auto-inner = %+v, want a child of auto-outer with 2 calls", i)
+	}
+	if other == nil || other.Parent != nil {
+		t.Errorf("auto-other = %+v, want a root timer", other)
+	}
+	if len(autoStacks) != 0 {
+		t.Errorf("autoStacks = %v, want empty", autoStacks)
+	}
+}
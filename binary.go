@@ -0,0 +1,154 @@
+package calltimer
+
+import (
+	"bufio"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"time"
+)
+
+// binaryMagic starts the output of ExportBinary.
+const binaryMagic = "CTB1"
+
+// maxBinaryName protects ImportBinary against allocating huge names from corrupt input.
+const maxBinaryName = 1 << 16
+
+/*
+ExportBinary writes all timers to w in a compact binary encoding, which is smaller and faster to parse than text formats. The data can be read back using ImportBinary().
+
+The encoding is the magic string "CTB1", followed by the number of root timers and each root timer. A timer is encoded as its name (length-prefixed), total elapsed nanoseconds, number of calls, slowest call in nanoseconds, number of children, and each child. All numbers are varints as in encoding/binary.
+*/
+func ExportBinary(w io.Writer) error {
+	return defaultRegistry.ExportBinary(w)
+}
+
+/*
+ExportBinary is like the package-level ExportBinary(), but writes the timers of this registry.
+*/
+func (r *Registry) ExportBinary(w io.Writer) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	bw := &binaryWriter{w: bufio.NewWriter(w)}
+	bw.w.WriteString(binaryMagic)
+	bw.uvarint(uint64(len(r.roots)))
+	for _, root := range r.roots {
+		bw.timer(root)
+	}
+	return bw.w.Flush()
+}
+
+/*
+ImportBinary reads timers that were written by ExportBinary() and returns them in a new registry. The imported timers don't clash with existing timers, and can be handled like any other registry, e.g. reported using its ReportAll(), or merged into existing timers using Merge():
+
+	reg, err := calltimer.ImportBinary(r)
+	...
+	reg.ReportAll(os.Stdout)
+*/
+func ImportBinary(r io.Reader) (*Registry, error) {
+	reg := NewRegistry()
+	reg.mu.Lock()
+	defer reg.mu.Unlock()
+
+	br := &binaryReader{r: bufio.NewReader(r), reg: reg}
+	magic := make([]byte, len(binaryMagic))
+	if _, err := io.ReadFull(br.r, magic); err != nil {
+		return nil, err
+	}
+	if string(magic) != binaryMagic {
+		return nil, errors.New("not a calltimer binary export")
+	}
+	n := br.uvarint()
+	for i := uint64(0); i < n && br.err == nil; i++ {
+		br.timer(nil)
+	}
+	if br.err != nil {
+		return nil, br.err
+	}
+	return reg, nil
+}
+
+type binaryWriter struct {
+	w   *bufio.Writer
+	buf [binary.MaxVarintLen64]byte
+}
+
+func (bw *binaryWriter) uvarint(v uint64) {
+	bw.w.Write(bw.buf[:binary.PutUvarint(bw.buf[:], v)])
+}
+
+func (bw *binaryWriter) varint(v int64) {
+	bw.w.Write(bw.buf[:binary.PutVarint(bw.buf[:], v)])
+}
+
+func (bw *binaryWriter) timer(t *Timer) {
+	s := t.copyStats()
+	bw.uvarint(uint64(len(s.Name)))
+	bw.w.WriteString(s.Name)
+	bw.varint(int64(s.TotalElapsed))
+	bw.uvarint(uint64(s.CalledTimes))
+	bw.varint(int64(s.MaxElapsed))
+	bw.uvarint(uint64(len(t.Children)))
+	for _, c := range t.Children {
+		bw.timer(c)
+	}
+}
+
+// binaryReader reads the encoding of ExportBinary, retaining the first error.
+type binaryReader struct {
+	r   *bufio.Reader
+	reg *Registry // Registry of the imported timers, which must be locked
+	err error
+}
+
+func (br *binaryReader) uvarint() uint64 {
+	if br.err != nil {
+		return 0
+	}
+	var v uint64
+	v, br.err = binary.ReadUvarint(br.r)
+	return v
+}
+
+func (br *binaryReader) varint() int64 {
+	if br.err != nil {
+		return 0
+	}
+	var v int64
+	v, br.err = binary.ReadVarint(br.r)
+	return v
+}
+
+// timer reads a timer and its descendants, and creates them under parent in the registry of br.
+func (br *binaryReader) timer(parent *Timer) {
+	n := br.uvarint()
+	if br.err == nil && n > maxBinaryName {
+		br.err = fmt.Errorf("timer name of %v bytes exceeds the maximum", n)
+	}
+	if br.err != nil {
+		return
+	}
+	name := make([]byte, n)
+	_, br.err = io.ReadFull(br.r, name)
+	total := time.Duration(br.varint())
+	calls := int(br.uvarint())
+	maxElapsed := time.Duration(br.varint())
+	children := br.uvarint()
+	if br.err == io.EOF {
+		br.err = io.ErrUnexpectedEOF
+	}
+	if br.err != nil {
+		return
+	}
+	t, err := br.reg.newTimer(string(name), parent)
+	if err != nil {
+		br.err = err
+		return
+	}
+	t.TotalElapsed, t.CalledTimes, t.MaxElapsed = total, calls, maxElapsed
+	for i := uint64(0); i < children && br.err == nil; i++ {
+		br.timer(t)
+	}
+}
@@ -0,0 +1,62 @@
+package calltimer
+
+import (
+	"bytes"
+	"encoding/binary"
+	"testing"
+	"time"
+)
+
+func TestBinaryRoundTrip(t *testing.T) {
+	resetGlobals()
+
+	r := MustNew("root", nil)
+	c := MustNew("child", r)
+	MustNew("other", nil)
+	r.LogDuration(time.Second)
+	c.LogDuration(time.Millisecond)
+	c.LogDuration(3 * time.Millisecond)
+
+	var b bytes.Buffer
+	if err := ExportBinary(&b); err != nil {
+		t.Fatalf("ExportBinary() = %v", err)
+	}
+	size := b.Len()
+	reg, err := ImportBinary(&b)
+	if err != nil {
+		t.Fatalf("ImportBinary() = %v", err)
+	}
+	got := reg.roots
+	if len(got) != 2 || got[0].Name != "root" || got[1].Name != "other" {
+		t.Fatalf("ImportBinary() = %v, want roots [root other]", got)
+	}
+	gc, ok := reg.Get("child")
+	if !ok || gc.Parent != got[0] || gc.TotalElapsed != 4*time.Millisecond ||
+		gc.CalledTimes != 2 || gc.MaxElapsed != 3*time.Millisecond {
+		t.Errorf("imported child = %+v, want a copy of %+v", gc, c)
+	}
+	var want, imported bytes.Buffer
+	ReportAll(&want)
+	reg.ReportAll(&imported)
+	if imported.String() != want.String() {
+		t.Errorf("ReportAll() of the imported registry = %q, want %q", imported.String(), want.String())
+	}
+
+	var truncated bytes.Buffer
+	ExportBinary(&truncated)
+	truncated.Truncate(size - 2)
+	if _, err := ImportBinary(&truncated); err == nil {
+		t.Error("ImportBinary() of truncated data succeeded, want error")
+	}
+}
+
+func TestImportBinaryOversizedName(t *testing.T) {
+	var b bytes.Buffer
+	b.WriteString(binaryMagic)
+	buf := make([]byte, binary.MaxVarintLen64)
+	b.Write(buf[:binary.PutUvarint(buf, 1)])
+	b.Write(buf[:binary.PutUvarint(buf, 1<<62)])
+	if _, err := ImportBinary(&b); err == nil {
+		t.Error("ImportBinary() of an oversized name length succeeded, want error")
+	}
+}
@@ -0,0 +1,83 @@
+package calltimer
+
+import (
+	"sync"
+	"time"
+)
+
+/*
+Clock is a source of the current time, see DefaultClock, Registry.SetClock() and Timer.SetClock().
+*/
+type Clock interface {
+	Now() time.Time
+}
+
+/*
+ClockFunc adapts a function to a Clock, e.g. for a simulation's virtual time:
+
+	simTimer.SetClock(calltimer.ClockFunc(func() time.Time { return sim.Now }))
+*/
+type ClockFunc func() time.Time
+
+/*
+Now returns f().
+*/
+func (f ClockFunc) Now() time.Time {
+	return f()
+}
+
+// realClock is the wall clock.
+type realClock struct{}
+
+func (realClock) Now() time.Time {
+	return time.Now()
+}
+
+/*
+DefaultClock is the time source of timers that have no clock of their own (see SetClock()) and whose registry has no clock (see Registry.SetClock()). It defaults to the wall clock. Tests can replace it, e.g. by a FakeClock, to verify totals and averages without sleeping. It should be replaced before timers are in use.
+*/
+var DefaultClock Clock = realClock{}
+
+/*
+FakeClock is a Clock that only moves when it's told to, which makes tests of timed code deterministic:
+
+	clock := calltimer.NewFakeClock(time.Unix(0, 0))
+	calltimer.DefaultClock = clock
+
+	start := tm.Now()
+	clock.Advance(time.Second)
+	tm.LogSince(start) // logs exactly 1s
+
+A FakeClock is safe for concurrent use.
+*/
+type FakeClock struct {
+	mu  sync.Mutex
+	now time.Time
+}
+
+/*
+NewFakeClock returns a FakeClock that's set to start.
+*/
+func NewFakeClock(start time.Time) *FakeClock {
+	return &FakeClock{now: start}
+}
+
+/*
+Now returns the time of the clock.
+*/
+func (c *FakeClock) Now() time.Time {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	return c.now
+}
+
+/*
+Advance moves the clock forward by d.
+*/
+func (c *FakeClock) Advance(d time.Duration) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.now = c.now.Add(d)
+}
@@ -0,0 +1,212 @@
+package calltimer
+
+import (
+	"cmp"
+	"fmt"
+	"slices"
+	"strconv"
+	"time"
+)
+
+// column is an optional report column, which is shown after the standard columns.
+type column struct {
+	label  string                   // Header in Table and CSV output
+	plain  string                   // Format of a cell in PlainText output, e.g. "%s of uptime"
+	legend string                   // Explanation for the legend, see ReportLegend
+	value  func(r ReportRow) string // Contents of a cell, "" when not applicable
+}
+
+// extraColumns returns the optional columns that are enabled for a report on the passed-in rows.
+func extraColumns(rows []ReportRow) []column {
+	var cols []column
+	if slices.ContainsFunc(rows, func(r ReportRow) bool { return r.Hidden > 0 }) {
+		cols = append(cols, column{
+			label:  "Hidden",
+			plain:  "%s hidden below",
+			legend: "number of timers below that are collapsed due to ReportMaxDepth",
+			value: func(r ReportRow) string {
+				if r.Hidden == 0 {
+					return ""
+				}
+				return strconv.Itoa(r.Hidden)
+			},
+		})
+	}
+	if ReportInclusiveExclusive {
+		cols = append(cols, column{
+			label:  "Exclusive time",
+			plain:  "%s exclusive",
+			legend: "total time minus the total time of the children, * when the children overlap, e.g. because they run in parallel",
+			value: func(r ReportRow) string {
+				if r.Overlap {
+					return DurationFormat(r.Self) + "*"
+				}
+				return DurationFormat(r.Self)
+			},
+		})
+	}
+	if ShowRank {
+		totals := make([]time.Duration, len(rows))
+		for i, r := range rows {
+			totals[i] = r.Total
+		}
+		slices.SortFunc(totals, func(a, b time.Duration) int { return cmp.Compare(b, a) })
+		cols = append(cols, column{
+			label:  "Rank",
+			plain:  "rank %s",
+			legend: "position when ordering all timers by total time, 1 is the highest",
+			value: func(r ReportRow) string {
+				// Timers with equal totals share a rank.
+				i, _ := slices.BinarySearchFunc(totals, r.Total, func(a, b time.Duration) int { return cmp.Compare(b, a) })
+				return fmt.Sprintf("%d", i+1)
+			},
+		})
+	}
+	if ShowUptimeShare {
+		up := Uptime()
+		cols = append(cols, column{
+			label:  "% of uptime",
+			plain:  "%s of uptime",
+			legend: "share of the process uptime",
+			value: func(r ReportRow) string {
+				if r.Depth > 0 {
+					return ""
+				}
+				return percentage(r.Total, up)
+			},
+		})
+	}
+	if ShowSiblingRatio {
+		hottest := map[*Timer]time.Duration{}
+		for _, r := range rows {
+			p := r.parent()
+			hottest[p] = max(hottest[p], r.Total)
+		}
+		cols = append(cols, column{
+			label:  "vs. hottest sibling",
+			plain:  "%s of hottest sibling",
+			legend: "total relative to the sibling with the highest total, which is 1.00x",
+			value: func(r ReportRow) string {
+				h := hottest[r.parent()]
+				if h <= 0 {
+					return ""
+				}
+				return fmt.Sprintf("%.2fx", float64(r.Total)/float64(h))
+			},
+		})
+	}
+	if ShowLast {
+		cols = append(cols, column{
+			label:  "Last",
+			plain:  "last %s",
+			legend: "duration of the most recent call",
+			value: func(r ReportRow) string {
+				if r.Calls == 0 {
+					return ""
+				}
+				return DurationFormat(r.stats.LastElapsed)
+			},
+		})
+	}
+	if ShowPeakInFlight {
+		cols = append(cols, column{
+			label:  "Peak in flight",
+			plain:  "peak %s in flight",
+			legend: "highest number of calls in progress at the same time",
+			value: func(r ReportRow) string {
+				return strconv.Itoa(r.stats.peakInFlight)
+			},
+		})
+	}
+	if ShowStdDev {
+		cols = append(cols, column{
+			label:  "Std. deviation",
+			plain:  "stddev %s",
+			legend: "standard deviation of the durations of the calls",
+			value: func(r ReportRow) string {
+				if r.stats.varCount < 2 {
+					return ""
+				}
+				return DurationFormat(r.stats.stdDev())
+			},
+		})
+	}
+	if ShowPercentages {
+		cols = append(cols, column{
+			label:  "% of parent",
+			plain:  "%s of parent",
+			legend: "share of the parent's total time",
+			value: func(r ReportRow) string {
+				if r.Depth == 0 {
+					return ""
+				}
+				return percentage(r.Total, r.parentTotal)
+			},
+		}, column{
+			label:  "% of root",
+			plain:  "%s of root",
+			legend: "share of the root's total time",
+			value: func(r ReportRow) string {
+				return percentage(r.Total, r.rootTotal)
+			},
+		})
+	}
+	if slices.ContainsFunc(rows, func(r ReportRow) bool { return !r.stats.windowStart.IsZero() }) {
+		cols = append(cols, throughputColumn())
+	}
+	if slices.ContainsFunc(rows, func(r ReportRow) bool { return r.stats.samples != nil }) {
+		for _, pc := range []struct {
+			label, legend string
+			p             float64
+		}{
+			{"Median", "half of the calls were faster", 50},
+			{"p95", "95% of the calls were faster", 95},
+			{"p99", "99% of the calls were faster", 99},
+		} {
+			pc := pc
+			cols = append(cols, column{
+				label:  pc.label,
+				plain:  pc.label + " %s",
+				legend: pc.legend + ", only for timers that track their distribution",
+				value: func(r ReportRow) string {
+					if len(r.stats.samples) == 0 {
+						return ""
+					}
+					return DurationFormat(r.stats.percentile(pc.p))
+				},
+			})
+		}
+	}
+	return cols
+}
+
+// wallColumn returns the column that shows the share of the roots in a wall-clock window.
+func wallColumn(wall time.Duration) column {
+	return column{
+		label:  "% of wall",
+		plain:  "%s of wall",
+		legend: "share of the passed-in wall-clock time",
+		value: func(r ReportRow) string {
+			if r.Depth > 0 {
+				return ""
+			}
+			return percentage(r.Total, wall)
+		},
+	}
+}
+
+// parent returns the parent of the row's timer within the report, which is nil for the reported roots.
+func (r ReportRow) parent() *Timer {
+	if r.Depth == 0 {
+		return nil
+	}
+	return r.Timer.Parent
+}
+
+// percentage formats part as a percentage of whole, or returns "" when whole isn't positive.
+func percentage(part, whole time.Duration) string {
+	if whole <= 0 {
+		return ""
+	}
+	return fmt.Sprintf("%.1f%%", float64(part)/float64(whole)*100)
+}
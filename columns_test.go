@@ -0,0 +1,272 @@
+package calltimer
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestShowSiblingRatio(t *testing.T) {
+	resetGlobals()
+	defer func() { OutputFormat, ShowSiblingRatio = Table, false }()
+
+	r := MustNew("root", nil)
+	MustNew("a", r).LogDuration(4 * time.Second)
+	MustNew("b", r).LogDuration(time.Second)
+	r.LogDuration(5 * time.Second)
+
+	OutputFormat = CSV
+	ShowSiblingRatio = true
+	var b bytes.Buffer
+	ReportAll(&b)
+	want := "Timer;Total;Calls;Average;vs. hottest sibling\n" +
+		"root;5s;1;5s;1.00x\n" +
+		"a;4s;1;4s;1.00x\n" +
+		"b;1s;1;1s;0.25x\n"
+	if b.String() != want {
+		t.Errorf("ReportAll() = %q, want %q", b.String(), want)
+	}
+}
+
+func TestReportInclusiveExclusive(t *testing.T) {
+	resetGlobals()
+	defer func() { OutputFormat, ReportInclusiveExclusive = Table, false }()
+
+	r := MustNew("root", nil)
+	MustNew("a", r).LogDuration(4 * time.Second)
+	MustNew("b", r).LogDuration(3 * time.Second)
+	r.LogDuration(5 * time.Second)
+
+	OutputFormat = CSV
+	ReportInclusiveExclusive = true
+	var b bytes.Buffer
+	ReportAll(&b)
+	want := "Timer;Total;Calls;Average;Exclusive time\n" +
+		"root;5s;1;5s;0s*\n" + // Children overlap
+		"a;4s;1;4s;4s\n" +
+		"b;3s;1;3s;3s\n"
+	if b.String() != want {
+		t.Errorf("ReportAll() = %q, want %q", b.String(), want)
+	}
+}
+
+func TestShowRank(t *testing.T) {
+	resetGlobals()
+	defer func() { OutputFormat, ShowRank = Table, false }()
+
+	r := MustNew("root", nil)
+	a := MustNew("a", r)
+	MustNew("deep", a).LogDuration(3 * time.Second)
+	a.LogDuration(3 * time.Second)
+	MustNew("b", r).LogDuration(4 * time.Second)
+	r.LogDuration(8 * time.Second)
+
+	OutputFormat = CSV
+	ShowRank = true
+	var b bytes.Buffer
+	ReportAll(&b)
+	want := "Timer;Total;Calls;Average;Rank\n" +
+		"root;8s;1;8s;1\n" +
+		"a;3s;1;3s;3\n" +
+		"deep;3s;1;3s;3\n" +
+		"b;4s;1;4s;2\n"
+	if b.String() != want {
+		t.Errorf("ReportAll() = %q, want %q", b.String(), want)
+	}
+}
+
+func TestShowPercentages(t *testing.T) {
+	resetGlobals()
+	defer func() { OutputFormat, ShowPercentages = Table, false }()
+
+	r := MustNew("root", nil)
+	a := MustNew("a", r)
+	MustNew("b", a).LogDuration(time.Second)
+	a.LogDuration(2 * time.Second)
+	r.LogDuration(8 * time.Second)
+
+	OutputFormat = CSV
+	ShowPercentages = true
+	var b bytes.Buffer
+	ReportAll(&b)
+	want := "Timer;Total;Calls;Average;% of parent;% of root\n" +
+		"root;8s;1;8s;;100.0%\n" +
+		"a;2s;1;2s;25.0%;25.0%\n" +
+		"b;1s;1;1s;50.0%;12.5%\n"
+	if b.String() != want {
+		t.Errorf("ReportAll() = %q, want %q", b.String(), want)
+	}
+}
+
+func TestShowLast(t *testing.T) {
+	resetGlobals()
+	defer func() { OutputFormat, ShowLast = Table, false }()
+
+	r := MustNew("root", nil)
+	MustNew("idle", r).Enter() // Entered, but never called
+	c := MustNew("child", r)
+	c.LogDuration(time.Second)
+	c.LogDuration(3 * time.Second)
+	r.LogDuration(5 * time.Second)
+	if c.LastElapsed != 3*time.Second {
+		t.Errorf("LastElapsed = %v, want 3s", c.LastElapsed)
+	}
+
+	OutputFormat = CSV
+	ShowLast = true
+	var b bytes.Buffer
+	ReportAll(&b)
+	want := "Timer;Total;Calls;Average;Last\n" +
+		"root;5s;1;5s;5s\n" +
+		"child;4s;2;2s;3s\n"
+	if b.String() != want {
+		t.Errorf("ReportAll() = %q, want %q", b.String(), want)
+	}
+}
+
+func TestShowAverage(t *testing.T) {
+	resetGlobals()
+	defer func(labels [4]string) {
+		OutputFormat, ShowAverage = Table, true
+		TimerLabel, TotalLabel, CallsLabel, AverageLabel = labels[0], labels[1], labels[2], labels[3]
+	}([4]string{TimerLabel, TotalLabel, CallsLabel, AverageLabel})
+
+	r := MustNew("root", nil)
+	MustNew("child", r).LogDuration(time.Second)
+	r.LogDuration(2 * time.Second)
+
+	ShowAverage = false
+	TimerLabel, TotalLabel, CallsLabel = "Timer", "Total", "#"
+	for _, test := range []struct {
+		format Format
+		want   string
+	}{
+		{
+			format: Table,
+			want: "+---------+-------+---+\n" +
+				"| Timer   | Total | # |\n" +
+				"+---------+-------+---+\n" +
+				"| root    |    2s | 1 |\n" +
+				"|   child |    1s | 1 |\n" +
+				"+---------+-------+---+\n",
+		},
+		{
+			format: PlainText,
+			want: "root    total 2s in 1 calls\n" +
+				"  child total 1s in 1 calls\n",
+		},
+		{
+			format: CSV,
+			want:   "Timer;Total;Calls\nroot;2s;1\nchild;1s;1\n",
+		},
+		{
+			format: JSON,
+			want: "[\n" +
+				"  {\n" +
+				"    \"name\": \"root\",\n" +
+				"    \"total_ns\": 2000000000,\n" +
+				"    \"calls\": 1,\n" +
+				"    \"children\": [\n" +
+				"      {\n" +
+				"        \"name\": \"child\",\n" +
+				"        \"total_ns\": 1000000000,\n" +
+				"        \"calls\": 1,\n" +
+				"        \"children\": []\n" +
+				"      }\n" +
+				"    ]\n" +
+				"  }\n" +
+				"]\n",
+		},
+	} {
+		OutputFormat = test.format
+		var b bytes.Buffer
+		ReportAll(&b)
+		if b.String() != test.want {
+			t.Errorf("ReportAll() in format %v = %q, want %q", test.format, b.String(), test.want)
+		}
+	}
+}
+
+func TestReportExclusive(t *testing.T) {
+	resetGlobals()
+	defer func() { OutputFormat, ReportExclusive, ShowPercentages = Table, false, false }()
+
+	r := MustNew("root", nil)
+	a := MustNew("a", r)
+	MustNew("b", a).LogDuration(time.Second)
+	a.LogDuration(3 * time.Second)
+	a.LogDuration(time.Second)
+	MustNew("parallel", r).LogDuration(20 * time.Second)
+	r.LogDuration(10 * time.Second)
+
+	OutputFormat = CSV
+	ReportExclusive = true
+	ShowPercentages = true
+	var b bytes.Buffer
+	ReportAll(&b)
+	want := "Timer;Exclusive;Calls;Average;% of parent;% of root\n" +
+		"root;0s;1;0s;;0.0%\n" +
+		"a;3s;2;1.5s;30.0%;30.0%\n" +
+		"b;1s;1;1s;25.0%;10.0%\n" +
+		"parallel;20s;1;20s;200.0%;200.0%\n"
+	if b.String() != want {
+		t.Errorf("ReportAll() = %q, want %q", b.String(), want)
+	}
+
+	OutputFormat = PlainText
+	ShowPercentages = false
+	b.Reset()
+	ReportAll(&b)
+	if !strings.Contains(b.String(), "exclusive  3s in 2 calls") || strings.Contains(b.String(), "total") {
+		t.Errorf("ReportAll() = %q, want exclusive times labeled as such", b.String())
+	}
+}
+
+func TestShowGrandTotal(t *testing.T) {
+	resetGlobals()
+	defer func() { OutputFormat, ShowGrandTotal, ShowLast = Table, false, false }()
+
+	a := MustNew("a", nil)
+	MustNew("child", a).LogDuration(time.Second)
+	a.LogDuration(3 * time.Second)
+	MustNew("b", nil).LogDuration(time.Second)
+
+	ShowGrandTotal = true
+	ShowLast = true
+	for _, test := range []struct {
+		format Format
+		want   string
+	}{
+		{
+			format: Table,
+			want: "+------------+------------+--------------+-------------------+------+\n" +
+				"| Timer name | Total time | Nr. of calls | Average time/call | Last |\n" +
+				"+------------+------------+--------------+-------------------+------+\n" +
+				"| a          |         3s |            1 |                3s |   3s |\n" +
+				"|   child    |         1s |            1 |                1s |   1s |\n" +
+				"+------------+------------+--------------+-------------------+------+\n" +
+				"+------------+------------+--------------+-------------------+------+\n" +
+				"| Timer name | Total time | Nr. of calls | Average time/call | Last |\n" +
+				"+------------+------------+--------------+-------------------+------+\n" +
+				"| b          |         1s |            1 |                1s |   1s |\n" +
+				"+------------+------------+--------------+-------------------+------+\n" +
+				"| TOTAL      |         4s |            2 |                2s |      |\n" +
+				"+------------+------------+--------------+-------------------+------+\n",
+		},
+		{
+			format: CSV,
+			want: "Timer;Total;Calls;Average;Last\n" +
+				"a;3s;1;3s;3s\n" +
+				"child;1s;1;1s;1s\n" +
+				"Timer;Total;Calls;Average;Last\n" +
+				"b;1s;1;1s;1s\n" +
+				"TOTAL;4s;2;2s;\n",
+		},
+	} {
+		OutputFormat = test.format
+		if got := ReportAllString(); got != test.want {
+			t.Errorf("ReportAll() in format %v = %q, want %q", test.format, got, test.want)
+		}
+	}
+}
@@ -0,0 +1,42 @@
+package calltimer
+
+import "context"
+
+// ctxKey is the key of the timer in a context.
+type ctxKey struct{}
+
+/*
+NewContext returns a copy of ctx that carries the timer, which can be retrieved downstream using FromContext(). This allows e.g. HTTP middleware to establish a timer per request, without passing the timer through every function.
+*/
+func NewContext(ctx context.Context, t *Timer) context.Context {
+	return context.WithValue(ctx, ctxKey{}, t)
+}
+
+/*
+FromContext returns the timer that ctx carries, or nil when there is none.
+*/
+func FromContext(ctx context.Context) *Timer {
+	t, _ := ctx.Value(ctxKey{}).(*Timer)
+	return t
+}
+
+/*
+TimeContext starts timing a child of the timer that ctx carries, or a root timer when ctx doesn't carry one. It returns a context that carries the child, so that further children can be added downstream, and a function that stops timing. For example:
+
+	func handle(ctx context.Context) {
+		ctx, stop := calltimer.TimeContext(ctx, "handle")
+		defer stop()
+		query(ctx) // may call TimeContext(ctx, "query"), which becomes a child of "handle"
+	}
+
+The timer is created on first use, and reused afterwards; like StartAuto(), a timer keeps the parent that it was created under. TimeContext panics when the timer can't be created, like MustNew.
+*/
+func TimeContext(ctx context.Context, name string) (context.Context, func()) {
+	parent := FromContext(ctx)
+	reg := defaultRegistry
+	if parent != nil {
+		reg = parent.registry()
+	}
+	t := reg.mustReuse(name, parent)
+	return NewContext(ctx, t), t.Start()
+}
@@ -0,0 +1,30 @@
+package calltimer
+
+import (
+	"context"
+	"testing"
+)
+
+func TestTimeContext(t *testing.T) {
+	resetGlobals()
+
+	if got := FromContext(context.Background()); got != nil {
+		t.Errorf("FromContext() of an empty context = %v, want nil", got)
+	}
+
+	for i := 0; i < 2; i++ {
+		ctx, stopReq := TimeContext(context.Background(), "request")
+		_, stopQuery := TimeContext(ctx, "query")
+		stopQuery()
+		stopReq()
+	}
+
+	req := defaultRegistry.timers["request"]
+	query := defaultRegistry.timers["query"]
+	if req == nil || query == nil || query.Parent != req {
+		t.Fatalf("timers request = %v, query = %v, want query under request", req, query)
+	}
+	if req.CalledTimes != 2 || query.CalledTimes != 2 {
+		t.Errorf("CalledTimes = %v and %v, want 2 and 2", req.CalledTimes, query.CalledTimes)
+	}
+}
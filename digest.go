@@ -0,0 +1,37 @@
+package calltimer
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"time"
+)
+
+/*
+DigestRounding is the granularity to which totals are rounded before they are hashed by Digest(). It defaults to a millisecond, so that trivial jitter doesn't change the digest. Set it to 0 to hash exact totals.
+*/
+var DigestRounding = time.Millisecond
+
+/*
+Digest returns a short hash of the structure and statistics of the timer and its children with activity: their names, nesting, number of calls, and totals, rounded to DigestRounding. Equal digests indicate that a profile hasn't changed, e.g. between CI runs, before comparing full reports. See also ReportDigest.
+*/
+func (t *Timer) Digest() string {
+	reg := t.registry()
+	reg.mu.Lock()
+	defer reg.mu.Unlock()
+
+	return digest(reportRows([]*Timer{t}, (*Timer).hasOwnActivity))
+}
+
+// digest returns the hash of the rows of a report.
+func digest(rows []ReportRow) string {
+	h := sha256.New()
+	for _, r := range rows {
+		total := r.Total
+		if DigestRounding > 0 {
+			total = total.Round(DigestRounding)
+		}
+		fmt.Fprintf(h, "%d %q %d %d\n", r.Depth, r.Name, total, r.Calls)
+	}
+	return hex.EncodeToString(h.Sum(nil))[:16]
+}
@@ -0,0 +1,36 @@
+package calltimer
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestDigest(t *testing.T) {
+	resetGlobals()
+	defer func() { ReportDigest = false }()
+
+	r := MustNew("root", nil)
+	c := MustNew("child", r)
+	r.LogDuration(time.Second)
+	c.LogDuration(time.Second)
+
+	d1 := r.Digest()
+	r.LogDuration(time.Microsecond) // Jitter, but also an extra call
+	d2 := r.Digest()
+	if d1 == d2 {
+		t.Error("Digest() didn't change after an extra call")
+	}
+	r.TotalElapsed += time.Microsecond // Only jitter
+	if d3 := r.Digest(); d3 != d2 {
+		t.Errorf("Digest() changed from %v to %v after jitter", d2, d3)
+	}
+
+	ReportDigest = true
+	var b bytes.Buffer
+	r.Report(&b)
+	if !strings.HasSuffix(b.String(), "Digest: "+d2+"\n") {
+		t.Errorf("Report() = %q, want digest %v in the footer", b.String(), d2)
+	}
+}
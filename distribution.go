@@ -0,0 +1,42 @@
+package calltimer
+
+import (
+	"math"
+	"slices"
+	"time"
+)
+
+/*
+TrackDistribution makes the timer retain the duration of each recorded invocation, so that Percentile() can compute e.g. the median or the p99 exactly. This costs 8 bytes per invocation, without limit, so it's meant for timers that aren't called too often, or for short runs. For hot timers, the option WithApproxPercentiles() uses a fixed amount of memory instead. When any reported timer tracks its distribution, reports show the columns Median, p95 and p99 for these timers.
+
+Durations that were logged before TrackDistribution() was called aren't included.
+*/
+func (t *Timer) TrackDistribution() {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if t.samples == nil {
+		t.samples = []time.Duration{}
+	}
+}
+
+/*
+Percentile returns the p-th percentile (0-100) of the durations of the timer, e.g. Percentile(50) is the median. The returned value is 0 when the timer doesn't track its distribution (see TrackDistribution()), or when it has no durations yet.
+*/
+func (t *Timer) Percentile(p float64) time.Duration {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	return t.percentile(p)
+}
+
+// percentile implements Percentile() using the nearest-rank method. The timer must be locked.
+func (t *Timer) percentile(p float64) time.Duration {
+	if len(t.samples) == 0 {
+		return 0
+	}
+	sorted := slices.Clone(t.samples)
+	slices.Sort(sorted)
+	rank := int(math.Ceil(p / 100 * float64(len(sorted))))
+	return sorted[min(max(rank, 1), len(sorted))-1]
+}
@@ -0,0 +1,50 @@
+package calltimer
+
+import (
+	"bytes"
+	"testing"
+	"time"
+)
+
+func TestPercentile(t *testing.T) {
+	resetGlobals()
+	defer func() { OutputFormat = Table }()
+
+	r := MustNew("root", nil)
+	tm := MustNew("tracked", r)
+	if got := tm.Percentile(50); got != 0 {
+		t.Errorf("Percentile() without tracking = %v, want 0", got)
+	}
+	tm.TrackDistribution()
+	for i := 100; i >= 1; i-- {
+		tm.LogDuration(time.Duration(i) * time.Millisecond)
+	}
+	r.LogDuration(time.Second)
+	for p, want := range map[float64]time.Duration{
+		0:   time.Millisecond,
+		50:  50 * time.Millisecond,
+		95:  95 * time.Millisecond,
+		99:  99 * time.Millisecond,
+		100: 100 * time.Millisecond,
+	} {
+		if got := tm.Percentile(p); got != want {
+			t.Errorf("Percentile(%v) = %v, want %v", p, got, want)
+		}
+	}
+
+	OutputFormat = CSV
+	var b bytes.Buffer
+	ReportAll(&b)
+	want := "Timer;Total;Calls;Average;Median;p95;p99\n" +
+		"root;1s;1;1s;;;\n" +
+		"tracked;5.05s;100;50.5ms;50ms;95ms;99ms\n"
+	if b.String() != want {
+		t.Errorf("ReportAll() = %q, want %q", b.String(), want)
+	}
+
+	tm.Reset()
+	tm.LogDuration(time.Second)
+	if got := tm.Percentile(50); got != time.Second {
+		t.Errorf("Percentile(50) after Reset() = %v, want 1s", got)
+	}
+}
@@ -0,0 +1,31 @@
+package calltimer
+
+import (
+	"bytes"
+	"testing"
+	"time"
+)
+
+func TestWriteDOT(t *testing.T) {
+	resetGlobals()
+
+	r := MustNew("root", nil)
+	MustNew("a", r).LogDuration(time.Second)
+	MustNew("idle", r)
+	r.LogDuration(4 * time.Second)
+
+	var b bytes.Buffer
+	if err := WriteDOT(&b); err != nil {
+		t.Fatalf("WriteDOT() = %v", err)
+	}
+	want := `digraph calltimer {
+  node [shape=box];
+  n0 [label="root\n4s in 1 calls"];
+  n1 [label="a\n1s in 1 calls"];
+  n0 -> n1 [label="25%", penwidth=2.0];
+}
+`
+	if b.String() != want {
+		t.Errorf("WriteDOT() = %q, want %q", b.String(), want)
+	}
+}
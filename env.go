@@ -0,0 +1,72 @@
+package calltimer
+
+import (
+	"fmt"
+	"os"
+	"path"
+	"strings"
+	"sync/atomic"
+)
+
+// EnableEnvVar is the environment variable that ConfigureFromEnv() reads.
+const EnableEnvVar = "CALLTIMER_ENABLE"
+
+// enablePatterns limits recording to matching timers, see SetEnabledPattern(). Recording is enabled for all timers when nil or empty. It's read when timers of any registry are created, so it isn't guarded by a registry's lock.
+var enablePatterns atomic.Pointer[[]string]
+
+/*
+ConfigureFromEnv limits recording to the timers that match the environment variable CALLTIMER_ENABLE, see SetEnabledPattern(). When the variable is unset or empty, recording is enabled on all timers. This allows to selectively switch on instrumentation of a subsystem without recompiling.
+*/
+func ConfigureFromEnv() error {
+	if err := SetEnabledPattern(os.Getenv(EnableEnvVar)); err != nil {
+		return fmt.Errorf("%s: %v", EnableEnvVar, err)
+	}
+	return nil
+}
+
+/*
+SetEnabledPattern limits recording to the timers that match glob, which holds one or more comma-separated glob patterns, e.g. "db.*,http.*". A timer matches when a pattern matches its name or its path (see Path()), using the syntax of path.Match. Recording is disabled on timers that don't match, both existing ones and timers that are created later, so that logging on them is a no-op. Of the existing timers, only those in the default registry are updated, see Registry. An empty glob enables recording on all timers.
+
+The global Active takes precedence: when it's false, nothing is recorded, regardless of the patterns.
+*/
+func SetEnabledPattern(glob string) error {
+	var patterns []string
+	for _, p := range strings.Split(glob, ",") {
+		if p = strings.TrimSpace(p); p == "" {
+			continue
+		}
+		if _, err := path.Match(p, ""); err != nil {
+			return fmt.Errorf("bad pattern %q: %v", p, err)
+		}
+		patterns = append(patterns, p)
+	}
+
+	defaultRegistry.mu.Lock()
+	defer defaultRegistry.mu.Unlock()
+
+	enablePatterns.Store(&patterns)
+	for _, t := range defaultRegistry.timers {
+		t.mu.Lock()
+		t.disabled = !t.enabledByPatterns()
+		t.mu.Unlock()
+	}
+	return nil
+}
+
+// enabledByPatterns returns true when recording is enabled by the patterns of SetEnabledPattern().
+func (t *Timer) enabledByPatterns() bool {
+	patterns := enablePatterns.Load()
+	if patterns == nil || len(*patterns) == 0 {
+		return true
+	}
+	p := t.Path()
+	for _, pattern := range *patterns {
+		if ok, _ := path.Match(pattern, t.Name); ok {
+			return true
+		}
+		if ok, _ := path.Match(pattern, p); ok {
+			return true
+		}
+	}
+	return false
+}
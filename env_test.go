@@ -0,0 +1,86 @@
+package calltimer
+
+import (
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestConfigureFromEnv(t *testing.T) {
+	resetGlobals()
+	defer enablePatterns.Store(nil)
+
+	main := MustNew("main", nil)
+	query := MustNew("db.query", main)
+
+	t.Setenv(EnableEnvVar, "db.*, main/http")
+	if err := ConfigureFromEnv(); err != nil {
+		t.Fatalf("ConfigureFromEnv() = %v", err)
+	}
+	http := MustNew("http", main)
+	other := MustNew("other", main)
+
+	for _, tm := range []*Timer{main, query, http, other} {
+		tm.LogDuration(time.Second)
+	}
+	for tm, want := range map[*Timer]int{main: 0, query: 1, http: 1, other: 0} {
+		if tm.CalledTimes != want {
+			t.Errorf("timer %q: CalledTimes = %v, want %v", tm.Name, tm.CalledTimes, want)
+		}
+	}
+
+	t.Setenv(EnableEnvVar, "")
+	ConfigureFromEnv()
+	main.LogDuration(time.Second)
+	if main.CalledTimes != 1 {
+		t.Errorf("after clearing %s: CalledTimes = %v, want 1", EnableEnvVar, main.CalledTimes)
+	}
+
+	t.Setenv(EnableEnvVar, "[")
+	if err := ConfigureFromEnv(); err == nil {
+		t.Error("ConfigureFromEnv() with a bad pattern = nil, want error")
+	}
+}
+
+func TestSetEnabledPattern(t *testing.T) {
+	resetGlobals()
+	defer enablePatterns.Store(nil)
+
+	db := MustNew("db.query", nil)
+	cache := MustNew("cache", nil)
+	if err := SetEnabledPattern("db.*"); err != nil {
+		t.Fatalf("SetEnabledPattern() = %v", err)
+	}
+	db.LogDuration(time.Second)
+	cache.LogDuration(time.Second)
+	if db.CalledTimes != 1 || cache.CalledTimes != 0 {
+		t.Errorf("CalledTimes of db.query, cache = %v, %v, want 1, 0", db.CalledTimes, cache.CalledTimes)
+	}
+	if err := SetEnabledPattern("db.[*"); err == nil {
+		t.Error("SetEnabledPattern() of a bad pattern = nil, want error")
+	}
+}
+
+// TestSetEnabledPatternConcurrently is meant to be run with -race.
+func TestSetEnabledPatternConcurrently(t *testing.T) {
+	resetGlobals()
+	defer enablePatterns.Store(nil)
+
+	reg := NewRegistry()
+	var wg sync.WaitGroup
+	wg.Add(2)
+	go func() {
+		defer wg.Done()
+		for i := 0; i < 100; i++ {
+			SetEnabledPattern(fmt.Sprintf("t%d", i))
+		}
+	}()
+	go func() {
+		defer wg.Done()
+		for i := 0; i < 100; i++ {
+			reg.MustNew(fmt.Sprintf("t%d", i), nil)
+		}
+	}()
+	wg.Wait()
+}
@@ -0,0 +1,85 @@
+package calltimer
+
+import (
+	"cmp"
+	"fmt"
+	"io"
+	"slices"
+	"text/tabwriter"
+)
+
+/*
+WriteFlat writes a flat list of the timers with activity, without the tree, most expensive first. Timers are listed by their name as it appears in their path, see Path(), so that the children that Child() creates under several parents are summed into one line. This shows the overall cost of e.g. a function that's timed under several parents:
+
+	apiTimer.Child("parse")   // "api/parse"
+	cronTimer.Child("parse")  // "cron/parse"
+
+The output is an aligned table, in which "parse" holds the totals and calls of both:
+
+	Timer  Total  Calls  Average
+	query  1.2s   40     30ms
+	parse  80ms   40     2ms
+*/
+func WriteFlat(wr io.Writer) error {
+	return defaultRegistry.WriteFlat(wr)
+}
+
+/*
+WriteFlat is like the package-level WriteFlat(), but writes the timers of this registry.
+*/
+func (r *Registry) WriteFlat(wr io.Writer) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	return writeFlat(wr, r.roots)
+}
+
+// writeFlat implements WriteFlat() for the passed-in timers and their descendants. The registry of the timers must be locked.
+func writeFlat(wr io.Writer, ts []*Timer) error {
+	tw := tabwriter.NewWriter(wr, 0, 0, 2, ' ', 0)
+	fmt.Fprint(tw, "Timer\tTotal\tCalls")
+	if ShowAverage {
+		fmt.Fprint(tw, "\tAverage")
+	}
+	fmt.Fprintln(tw)
+	for _, f := range flatten(ts) {
+		if !f.hasOwnActivity() {
+			continue
+		}
+		fmt.Fprintf(tw, "%s\t%s\t%d", f.Name, DurationFormat(f.TotalElapsed), f.CalledTimes)
+		if ShowAverage {
+			var avg string
+			if f.CalledTimes > 0 {
+				avg = DurationFormat(average(f.TotalElapsed, f.CalledTimes))
+			}
+			fmt.Fprintf(tw, "\t%s", avg)
+		}
+		fmt.Fprintln(tw)
+	}
+	return tw.Flush()
+}
+
+// flatten returns the statistics of the passed-in timers and their descendants, summed per name in the path and sorted by total time. The registry of the timers must be locked.
+func flatten(ts []*Timer) []*Timer {
+	var flat []*Timer
+	byName := map[string]*Timer{}
+	var walk func(ts []*Timer)
+	walk = func(ts []*Timer) {
+		for _, t := range ts {
+			s := t.copyStats()
+			name := t.pathName()
+			f, ok := byName[name]
+			if !ok {
+				f = &Timer{Name: name, Children: []*Timer{}}
+				byName[name] = f
+				flat = append(flat, f)
+			}
+			f.TotalElapsed += s.TotalElapsed
+			f.CalledTimes += s.CalledTimes
+			walk(t.Children)
+		}
+	}
+	walk(ts)
+	slices.SortStableFunc(flat, func(a, b *Timer) int { return cmp.Compare(b.TotalElapsed, a.TotalElapsed) })
+	return flat
+}
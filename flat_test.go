@@ -0,0 +1,59 @@
+package calltimer
+
+import (
+	"bytes"
+	"testing"
+	"time"
+)
+
+func TestWriteFlat(t *testing.T) {
+	resetGlobals()
+
+	r := MustNew("root", nil)
+	a := MustNew("a", r)
+	b := MustNew("b", a)
+	MustNew("idle", r)
+	r.LogDuration(10 * time.Millisecond)
+	a.LogDuration(2 * time.Millisecond)
+	b.LogDuration(5 * time.Millisecond)
+	b.LogDuration(time.Millisecond)
+
+	var buf bytes.Buffer
+	if err := WriteFlat(&buf); err != nil {
+		t.Fatalf("WriteFlat() = %v, want nil", err)
+	}
+	want := "Timer  Total  Calls  Average\n" +
+		"root   10ms   1      10ms\n" +
+		"b      6ms    2      3ms\n" +
+		"a      2ms    1      2ms\n"
+	if buf.String() != want {
+		t.Errorf("WriteFlat() = %q, want %q", buf.String(), want)
+	}
+
+	// Children of Child() under several parents are summed.
+	resetGlobals()
+	MustNew("api", nil).Child("parse").LogDuration(time.Millisecond)
+	MustNew("cron", nil).Child("parse").LogDuration(3 * time.Millisecond)
+	buf.Reset()
+	WriteFlat(&buf)
+	want = "Timer  Total  Calls  Average\n" +
+		"parse  4ms    2      2ms\n"
+	if buf.String() != want {
+		t.Errorf("WriteFlat() of children under several parents = %q, want %q", buf.String(), want)
+	}
+}
+
+func TestWriteFlatWithoutAverage(t *testing.T) {
+	resetGlobals()
+	defer func() { ShowAverage = true }()
+
+	MustNew("root", nil).LogDuration(10 * time.Millisecond)
+	ShowAverage = false
+	var buf bytes.Buffer
+	WriteFlat(&buf)
+	want := "Timer  Total  Calls\n" +
+		"root   10ms   1\n"
+	if buf.String() != want {
+		t.Errorf("WriteFlat() without average = %q, want %q", buf.String(), want)
+	}
+}
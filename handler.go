@@ -0,0 +1,100 @@
+package calltimer
+
+import (
+	"fmt"
+	"net/http"
+)
+
+// formatNames maps the values of the query parameter "format" of Handler() to formats.
+var formatNames = map[string]Format{
+	"table": Table,
+	"plain": PlainText,
+	"csv":   CSV,
+	"json":  JSON,
+	"yaml":  YAML,
+	"tsv":   TSV,
+	"html":  HTML,
+	"jsonl": JSONLines,
+}
+
+/*
+Handler returns an HTTP handler that serves a report of all timers, like ReportAll, for live debugging of a running service, much like the endpoints of net/http/pprof:
+
+	http.Handle("/debug/calltimer", calltimer.Handler())
+
+The report uses OutputFormat, unless the request overrides it using the query parameter "format", which is one of "table", "plain", "csv", "json", "yaml", "tsv", "html" or "jsonl". The report is safe while the service keeps logging.
+*/
+func Handler() http.Handler {
+	return defaultRegistry.Handler()
+}
+
+/*
+Handler is like the package-level Handler(), but serves a report of the timers of this registry.
+*/
+func (r *Registry) Handler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		format := OutputFormat
+		if name := req.URL.Query().Get("format"); name != "" {
+			f, ok := formatNames[name]
+			if !ok {
+				http.Error(w, fmt.Sprintf("unknown format %q", name), http.StatusBadRequest)
+				return
+			}
+			format = f
+		}
+		switch format {
+		case JSON:
+			w.Header().Set("Content-Type", "application/json")
+		case CSV:
+			w.Header().Set("Content-Type", "text/csv; charset=utf-8")
+		case YAML:
+			w.Header().Set("Content-Type", "application/yaml")
+		case TSV:
+			w.Header().Set("Content-Type", "text/tab-separated-values; charset=utf-8")
+		case HTML:
+			w.Header().Set("Content-Type", "text/html; charset=utf-8")
+		case JSONLines:
+			w.Header().Set("Content-Type", "application/x-ndjson")
+		default:
+			w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+		}
+		if !Active {
+			return
+		}
+
+		r.mu.Lock()
+		defer r.mu.Unlock()
+
+		bw, flush := reportWriter(w)
+		s := newTextSink(bw, w)
+		s.format = format
+		sendReport(s, r.roots, (*Timer).hasOwnActivity)
+		flush()
+	})
+}
+
+/*
+WithTimer returns an HTTP handler that serves requests using next, and logs the duration of each request on t:
+
+	apiTimer := calltimer.MustNew("api", nil)
+	mux.Handle("/api/", calltimer.WithTimer(apiTimer, apiHandler))
+*/
+func WithTimer(t *Timer, next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		defer t.LogSince(t.Now())
+		next.ServeHTTP(w, req)
+	})
+}
+
+/*
+WithRouteTimer is like WithTimer, but logs the requests on a child of parent that's named after the route, so that reports break down the time per route:
+
+	httpTimer := calltimer.MustNew("http", nil)
+	mux.Handle("/users", calltimer.WithRouteTimer(httpTimer, "/users", usersHandler))
+	mux.Handle("/orders", calltimer.WithRouteTimer(httpTimer, "/orders", ordersHandler))
+
+The child is created in the registry of parent when WithRouteTimer is called, so the route must be a unique timer name. WithRouteTimer panics when the child can't be created, like MustNew.
+*/
+func WithRouteTimer(parent *Timer, route string, next http.Handler) http.Handler {
+	return WithTimer(parent.registry().MustNew(route, parent), next)
+}
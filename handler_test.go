@@ -0,0 +1,60 @@
+package calltimer
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestHandler(t *testing.T) {
+	resetGlobals()
+
+	MustNew("root", nil).LogDuration(time.Second)
+	for _, test := range []struct {
+		query      string
+		wantStatus int
+		wantPrefix string
+	}{
+		{"", http.StatusOK, "+-"},
+		{"?format=csv", http.StatusOK, "Timer;Total;Calls;Average\n"},
+		{"?format=json", http.StatusOK, "[\n  {\n    \"name\": \"root\""},
+		{"?format=xml", http.StatusBadRequest, "unknown format"},
+	} {
+		rec := httptest.NewRecorder()
+		Handler().ServeHTTP(rec, httptest.NewRequest("GET", "/"+test.query, nil))
+		if rec.Code != test.wantStatus || !strings.HasPrefix(rec.Body.String(), test.wantPrefix) {
+			t.Errorf("GET %q = %v %q, want %v starting with %q", test.query, rec.Code, rec.Body.String(), test.wantStatus, test.wantPrefix)
+		}
+	}
+	if OutputFormat != Table {
+		t.Errorf("OutputFormat = %v after requests, want it unchanged", OutputFormat)
+	}
+}
+
+func TestWithRouteTimer(t *testing.T) {
+	resetGlobals()
+	defer func() { DefaultClock = realClock{} }()
+	clock := NewFakeClock(time.Unix(0, 0))
+	DefaultClock = clock
+
+	slow := http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		clock.Advance(time.Second)
+	})
+	httpTimer := MustNew("http", nil)
+	mux := http.NewServeMux()
+	mux.Handle("/users", WithRouteTimer(httpTimer, "/users", slow))
+	mux.Handle("/orders", WithRouteTimer(httpTimer, "/orders", slow))
+	h := WithTimer(httpTimer, mux)
+	for _, path := range []string{"/users", "/users", "/orders"} {
+		h.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", path, nil))
+	}
+
+	for name, want := range map[string]time.Duration{"http": 3 * time.Second, "/users": 2 * time.Second, "/orders": time.Second} {
+		tm, _ := Get(name)
+		if tm.TotalElapsed != want {
+			t.Errorf("timer %q: TotalElapsed = %v, want %v", name, tm.TotalElapsed, want)
+		}
+	}
+}
@@ -0,0 +1,43 @@
+package calltimer
+
+import (
+	"fmt"
+	"html"
+	"io"
+	"strings"
+	"time"
+)
+
+// reportHTML writes the rows as nested lists. Timers with children are collapsible using <details>, and all parts have CSS classes for styling.
+func reportHTML(wr io.Writer, rows []ReportRow) {
+	fmt.Fprintln(wr, `<ul class="calltimer">`)
+	writeHTML(wr, tree(rows), "  ")
+	fmt.Fprintln(wr, `</ul>`)
+}
+
+func writeHTML(wr io.Writer, nodes []*treeNode, indent string) {
+	for _, n := range nodes {
+		if len(n.Children) == 0 {
+			fmt.Fprintf(wr, "%s<li class=\"calltimer-timer\">%s</li>\n", indent, htmlStats(n))
+			continue
+		}
+		fmt.Fprintf(wr, "%s<li class=\"calltimer-timer\"><details open><summary>%s</summary>\n", indent, htmlStats(n))
+		fmt.Fprintf(wr, "%s  <ul>\n", indent)
+		writeHTML(wr, n.Children, indent+"    ")
+		fmt.Fprintf(wr, "%s  </ul>\n", indent)
+		fmt.Fprintf(wr, "%s</details></li>\n", indent)
+	}
+}
+
+// htmlStats returns the name and statistics of a timer as escaped HTML spans.
+func htmlStats(n *treeNode) string {
+	parts := []string{
+		fmt.Sprintf(`<span class="calltimer-name">%s</span>`, html.EscapeString(n.Name)),
+		fmt.Sprintf(`<span class="calltimer-total">%s</span>`, html.EscapeString(DurationFormat(time.Duration(n.TotalNs)))),
+		fmt.Sprintf(`<span class="calltimer-calls">%d</span>`, n.Calls),
+	}
+	if n.AvgNs != nil && n.Calls > 0 {
+		parts = append(parts, fmt.Sprintf(`<span class="calltimer-avg">%s</span>`, html.EscapeString(DurationFormat(time.Duration(*n.AvgNs)))))
+	}
+	return strings.Join(parts, " ")
+}
@@ -0,0 +1,32 @@
+package calltimer
+
+import (
+	"bytes"
+	"testing"
+	"time"
+)
+
+func TestHTML(t *testing.T) {
+	resetGlobals()
+	defer func() { OutputFormat = Table }()
+
+	r := MustNew("root", nil)
+	MustNew("<b>&co</b>", r).LogDuration(time.Second)
+	MustNew("idle", r)
+	r.LogDuration(2 * time.Second)
+
+	OutputFormat = HTML
+	var b bytes.Buffer
+	ReportAll(&b)
+	want := `<ul class="calltimer">
+  <li class="calltimer-timer"><details open><summary><span class="calltimer-name">root</span> <span class="calltimer-total">2s</span> <span class="calltimer-calls">1</span> <span class="calltimer-avg">2s</span></summary>
+    <ul>
+      <li class="calltimer-timer"><span class="calltimer-name">&lt;b&gt;&amp;co&lt;/b&gt;</span> <span class="calltimer-total">1s</span> <span class="calltimer-calls">1</span> <span class="calltimer-avg">1s</span></li>
+    </ul>
+  </details></li>
+</ul>
+`
+	if b.String() != want {
+		t.Errorf("ReportAll() = %q, want %q", b.String(), want)
+	}
+}
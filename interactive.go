@@ -0,0 +1,113 @@
+package calltimer
+
+import (
+	"bufio"
+	"cmp"
+	"fmt"
+	"io"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+const interactiveHelp = `Commands:
+  list         list the paths of all timers
+  show <path>  report on the timer with the given path, and its children
+  top <n>      list the n timers with the highest totals
+  reset        clear the statistics of all timers
+  help         show this text
+  quit         stop`
+
+/*
+Interactive reads commands from r and writes the results to w, until r is exhausted or the command "quit" is read. This allows to query timers ad hoc, e.g. from a debug console or over a unix socket of a running process. The commands are:
+
+	list         list the paths of all timers
+	show <path>  report on the timer with the given path, and its children
+	top <n>      list the n timers with the highest totals
+	reset        clear the statistics of all timers
+	help         show the commands
+	quit         stop
+
+Reports by "show" use the current OutputFormat.
+*/
+func Interactive(r io.Reader, w io.Writer) {
+	defaultRegistry.Interactive(r, w)
+}
+
+/*
+Interactive is like the package-level Interactive(), but queries the timers of this registry.
+*/
+func (r *Registry) Interactive(in io.Reader, w io.Writer) {
+	sc := bufio.NewScanner(in)
+	for sc.Scan() {
+		fields := strings.Fields(sc.Text())
+		if len(fields) == 0 {
+			continue
+		}
+		cmd, args := fields[0], fields[1:]
+		switch {
+		case cmd == "list" && len(args) == 0:
+			for _, t := range r.FilterTimers(func(*Timer, TimerStats) bool { return true }) {
+				fmt.Fprintln(w, t.Path())
+			}
+		case cmd == "show" && len(args) == 1:
+			t := r.lookupPath(args[0])
+			if t == nil {
+				fmt.Fprintf(w, "no timer with path %q\n", args[0])
+				continue
+			}
+			t.Report(w)
+		case cmd == "top" && len(args) == 1:
+			n, err := strconv.Atoi(args[0])
+			if err != nil || n < 1 {
+				fmt.Fprintf(w, "top: %q is not a positive number\n", args[0])
+				continue
+			}
+			r.interactiveTop(w, n)
+		case cmd == "reset" && len(args) == 0:
+			r.ResetAll()
+			fmt.Fprintln(w, "all timers are reset")
+		case cmd == "help":
+			fmt.Fprintln(w, interactiveHelp)
+		case cmd == "quit":
+			return
+		default:
+			fmt.Fprintf(w, "bad command %q, try \"help\"\n", sc.Text())
+		}
+	}
+}
+
+// interactiveTop lists the n timers with the highest totals.
+func (r *Registry) interactiveTop(w io.Writer, n int) {
+	var active []walkEntry
+	r.Walk(func(t *Timer, s TimerStats, _ int) bool {
+		if s.CalledTimes > 0 || s.TotalElapsed > 0 {
+			active = append(active, walkEntry{t: t, stats: s})
+		}
+		return true
+	})
+	slices.SortStableFunc(active, func(a, b walkEntry) int {
+		return cmp.Compare(b.stats.TotalElapsed, a.stats.TotalElapsed)
+	})
+	for i, e := range active[:min(n, len(active))] {
+		fmt.Fprintf(w, "%d. %s total %s in %d calls\n", i+1, e.t.Path(), DurationFormat(e.stats.TotalElapsed), e.stats.CalledTimes)
+	}
+}
+
+// lookupPath returns the timer with the given path, see Path(), or nil.
+func (r *Registry) lookupPath(path string) *Timer {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	candidates := r.roots
+	var t *Timer
+	for _, name := range strings.Split(path, PathSeparator) {
+		i := slices.IndexFunc(candidates, func(c *Timer) bool { return c.pathName() == name })
+		if i < 0 {
+			return nil
+		}
+		t = candidates[i]
+		candidates = t.Children
+	}
+	return t
+}
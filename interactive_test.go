@@ -0,0 +1,66 @@
+package calltimer
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestInteractive(t *testing.T) {
+	resetGlobals()
+	defer func() { OutputFormat = Table }()
+	OutputFormat = CSV
+
+	r := MustNew("root", nil)
+	c := MustNew("child", r)
+	r.LogDuration(3 * time.Second)
+	c.LogDuration(time.Second)
+
+	in := strings.Join([]string{
+		"list",
+		"show root/child",
+		"show root/nope",
+		"top 1",
+		"top x",
+		"bogus",
+		"reset",
+		"top 5",
+		"quit",
+		"list",
+	}, "\n")
+	var out bytes.Buffer
+	Interactive(strings.NewReader(in), &out)
+	want := strings.Join([]string{
+		"root",
+		"root/child",
+		"Timer;Total;Calls;Average",
+		"child;1s;1;1s",
+		`no timer with path "root/nope"`,
+		"1. root total 3s in 1 calls",
+		`top: "x" is not a positive number`,
+		`bad command "bogus", try "help"`,
+		"all timers are reset",
+		"",
+	}, "\n")
+	if out.String() != want {
+		t.Errorf("Interactive() wrote:\n%s\nwant:\n%s", out.String(), want)
+	}
+}
+
+func TestInteractiveChild(t *testing.T) {
+	resetGlobals()
+	defer func() { OutputFormat = Table }()
+	OutputFormat = CSV
+
+	c := MustNew("query", nil).Child("getUser")
+	c.LogDuration(time.Second)
+	if got := c.Path(); got != "query/getUser" {
+		t.Fatalf("Path() = %q, want %q", got, "query/getUser")
+	}
+	var out bytes.Buffer
+	Interactive(strings.NewReader("show "+c.Path()), &out)
+	if want := "Timer;Total;Calls;Average\nquery/getUser;1s;1;1s\n"; out.String() != want {
+		t.Errorf("show %s wrote %q, want %q", c.Path(), out.String(), want)
+	}
+}
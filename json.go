@@ -0,0 +1,124 @@
+package calltimer
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"slices"
+	"strconv"
+)
+
+// treeNode is a timer in a JSON or YAML report.
+type treeNode struct {
+	Name     string            `json:"name"`
+	TotalNs  int64             `json:"total_ns"`
+	Calls    int               `json:"calls"`
+	AvgNs    *int64            `json:"avg_ns,omitempty"`         // nil unless ShowAverage
+	Slowest  map[string]string `json:"slowest_fields,omitempty"` // See SlowestFields()
+	Children []*treeNode       `json:"children"`
+}
+
+// tree returns the rows as nested nodes, one per root. Durations are integer nanoseconds.
+func tree(rows []ReportRow) []*treeNode {
+	nodes := []*treeNode{}
+	var stack []*treeNode // Ancestors of the current row, by depth
+	for _, r := range rows {
+		n := &treeNode{
+			Name:     r.Name,
+			TotalNs:  r.Total.Nanoseconds(),
+			Calls:    r.Calls,
+			Slowest:  r.stats.slowestFields,
+			Children: []*treeNode{},
+		}
+		if ShowAverage {
+			avg := r.Average.Nanoseconds()
+			n.AvgNs = &avg
+		}
+		stack = append(stack[:r.Depth], n)
+		if r.Depth == 0 {
+			nodes = append(nodes, n)
+		} else {
+			p := stack[r.Depth-1]
+			p.Children = append(p.Children, n)
+		}
+	}
+	return nodes
+}
+
+// reportJSON writes the rows as an array of nested root objects.
+func reportJSON(wr io.Writer, rows []ReportRow) {
+	enc := json.NewEncoder(wr)
+	enc.SetIndent("", "  ")
+	enc.Encode(tree(rows))
+}
+
+// jsonLine is a timer in a JSONLines report. Parent is empty for the reported roots.
+type jsonLine struct {
+	Name    string            `json:"name"`
+	Depth   int               `json:"depth"`
+	Parent  string            `json:"parent"`
+	TotalNs int64             `json:"total_ns"`
+	Calls   int               `json:"calls"`
+	AvgNs   *int64            `json:"avg_ns,omitempty"`         // nil unless ShowAverage
+	Slowest map[string]string `json:"slowest_fields,omitempty"` // See SlowestFields()
+}
+
+// jsonLinesWriter writes rows as one JSON object per line as they arrive, so that a JSONLines report doesn't hold the rows of the whole tree.
+type jsonLinesWriter struct {
+	enc   *json.Encoder
+	names []string // Names of the ancestors of the current row, by depth
+}
+
+// row writes a line for the row.
+func (w *jsonLinesWriter) row(r ReportRow) {
+	l := jsonLine{Name: r.Name, Depth: r.Depth, TotalNs: r.Total.Nanoseconds(), Calls: r.Calls, Slowest: r.stats.slowestFields}
+	if r.Depth > 0 {
+		l.Parent = w.names[r.Depth-1]
+	}
+	w.names = append(w.names[:r.Depth], r.Name)
+	if ShowAverage {
+		avg := r.Average.Nanoseconds()
+		l.AvgNs = &avg
+	}
+	w.enc.Encode(l)
+}
+
+// reportYAML writes the rows as a sequence of nested root mappings, with the same keys as reportJSON.
+func reportYAML(wr io.Writer, rows []ReportRow) {
+	nodes := tree(rows)
+	if len(nodes) == 0 {
+		fmt.Fprintln(wr, "[]")
+		return
+	}
+	writeYAML(wr, nodes, "")
+}
+
+// writeYAML writes nodes as a block sequence, indented by indent.
+func writeYAML(wr io.Writer, nodes []*treeNode, indent string) {
+	for _, n := range nodes {
+		// Names are double-quoted, which is valid YAML for Go's escapes of special characters.
+		fmt.Fprintf(wr, "%s- name: %s\n", indent, strconv.Quote(n.Name))
+		in := indent + "  "
+		fmt.Fprintf(wr, "%stotal_ns: %d\n%scalls: %d\n", in, n.TotalNs, in, n.Calls)
+		if n.AvgNs != nil {
+			fmt.Fprintf(wr, "%savg_ns: %d\n", in, *n.AvgNs)
+		}
+		if len(n.Slowest) > 0 {
+			fmt.Fprintf(wr, "%sslowest_fields:\n", in)
+			keys := make([]string, 0, len(n.Slowest))
+			for k := range n.Slowest {
+				keys = append(keys, k)
+			}
+			slices.Sort(keys)
+			for _, k := range keys {
+				fmt.Fprintf(wr, "%s  %s: %s\n", in, strconv.Quote(k), strconv.Quote(n.Slowest[k]))
+			}
+		}
+		if len(n.Children) == 0 {
+			fmt.Fprintf(wr, "%schildren: []\n", in)
+			continue
+		}
+		fmt.Fprintf(wr, "%schildren:\n", in)
+		writeYAML(wr, n.Children, in+"  ")
+	}
+}
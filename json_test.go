@@ -0,0 +1,159 @@
+package calltimer
+
+import (
+	"bytes"
+	"encoding/json"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestJSON(t *testing.T) {
+	resetGlobals()
+	defer func() { OutputFormat = Table }()
+	OutputFormat = JSON
+
+	var b bytes.Buffer
+	ReportAll(&b)
+	if got := b.String(); got != "[]\n" {
+		t.Errorf("empty ReportAll() = %q, want []", got)
+	}
+
+	r := MustNew("root", nil)
+	c := MustNew("child", r)
+	MustNew("idle", r)
+	r.LogDuration(3 * time.Second)
+	c.LogDuration(time.Second)
+	c.LogDuration(time.Second)
+
+	b.Reset()
+	ReportAll(&b)
+	var got []treeNode
+	if err := json.Unmarshal(b.Bytes(), &got); err != nil {
+		t.Fatalf("ReportAll() = %q: %v", b.String(), err)
+	}
+	if len(got) != 1 || got[0].Name != "root" || got[0].TotalNs != int64(3*time.Second) || got[0].Calls != 1 {
+		t.Fatalf("ReportAll() = %+v, want a single root", got)
+	}
+	if ch := got[0].Children; len(ch) != 1 || ch[0].Name != "child" || ch[0].Calls != 2 || ch[0].AvgNs == nil || *ch[0].AvgNs != int64(time.Second) {
+		t.Errorf("children = %+v, want only the active child", ch)
+	}
+}
+
+func TestYAML(t *testing.T) {
+	resetGlobals()
+	defer func() { OutputFormat = Table }()
+	OutputFormat = YAML
+
+	var b bytes.Buffer
+	ReportAll(&b)
+	if got := b.String(); got != "[]\n" {
+		t.Errorf("empty ReportAll() = %q, want []", got)
+	}
+
+	r := MustNew("root", nil)
+	c := MustNew(`say "hi"`, r)
+	MustNew("idle", r)
+	r.LogDuration(3 * time.Second)
+	c.LogDuration(time.Second)
+
+	b.Reset()
+	ReportAll(&b)
+	want := `- name: "root"
+  total_ns: 3000000000
+  calls: 1
+  avg_ns: 3000000000
+  children:
+    - name: "say \"hi\""
+      total_ns: 1000000000
+      calls: 1
+      avg_ns: 1000000000
+      children: []
+`
+	if b.String() != want {
+		t.Errorf("ReportAll() = %q, want %q", b.String(), want)
+	}
+}
+
+func TestJSONLines(t *testing.T) {
+	resetGlobals()
+	defer func() { OutputFormat = Table }()
+	OutputFormat = JSONLines
+
+	var b bytes.Buffer
+	ReportAll(&b)
+	if got := b.String(); got != "" {
+		t.Errorf("empty ReportAll() = %q, want no lines", got)
+	}
+
+	r := MustNew("root", nil)
+	c := MustNew("child", r)
+	MustNew("idle", r)
+	r.LogDuration(3 * time.Second)
+	c.LogDuration(time.Second)
+	c.LogDuration(time.Second)
+
+	b.Reset()
+	ReportAll(&b)
+	want := `{"name":"root","depth":0,"parent":"","total_ns":3000000000,"calls":1,"avg_ns":3000000000}
+{"name":"child","depth":1,"parent":"root","total_ns":2000000000,"calls":2,"avg_ns":1000000000}
+`
+	if got := b.String(); got != want {
+		t.Errorf("ReportAll() = %q, want %q", got, want)
+	}
+}
+
+func TestJSONLinesStreams(t *testing.T) {
+	resetGlobals()
+	defer func() { OutputFormat = Table }()
+	OutputFormat = JSONLines
+
+	r := MustNew("root", nil)
+	r.LogDuration(time.Second)
+
+	var b bytes.Buffer
+	s := newTextSink(&b, &b)
+	s.Begin()
+	walkRows([]*Timer{r}, (*Timer).hasOwnActivity, s.Row)
+	if b.Len() == 0 {
+		t.Error("JSONLines rows weren't written before End()")
+	}
+	if len(s.rows) != 0 {
+		t.Errorf("JSONLines sink holds %v rows, want 0", len(s.rows))
+	}
+	s.End()
+}
+
+func TestSlowestFieldsInReports(t *testing.T) {
+	resetGlobals()
+	defer func() { OutputFormat = Table }()
+
+	tm := MustNew("query", nil)
+	tm.LogSinceCtx(time.Now().Add(-time.Second), map[string]string{"id": "slow"})
+	tm.LogSinceCtx(time.Now(), map[string]string{"id": "fast"})
+
+	OutputFormat = JSON
+	var b bytes.Buffer
+	ReportAll(&b)
+	var nodes []treeNode
+	if err := json.Unmarshal(b.Bytes(), &nodes); err != nil {
+		t.Fatalf("ReportAll() = %q: %v", b.String(), err)
+	}
+	if len(nodes) != 1 || nodes[0].Slowest["id"] != "slow" {
+		t.Errorf("JSON report = %+v, want slowest_fields id=slow", nodes)
+	}
+
+	OutputFormat = JSONLines
+	b.Reset()
+	ReportAll(&b)
+	if !strings.Contains(b.String(), `"slowest_fields":{"id":"slow"}`) {
+		t.Errorf("JSONLines report = %q, want slowest_fields id=slow", b.String())
+	}
+
+	OutputFormat = YAML
+	b.Reset()
+	ReportAll(&b)
+	if !strings.Contains(b.String(), "  slowest_fields:\n    \"id\": \"slow\"\n") {
+		t.Errorf("YAML report = %q, want slowest_fields id=slow", b.String())
+	}
+}
@@ -0,0 +1,65 @@
+package calltimer
+
+import "fmt"
+
+/*
+Merge adds the statistics of other and its descendants to t, e.g. to combine the timers of shards that ran the same workload in separate registries:
+
+	combined := calltimer.MustNew("all", nil)
+	for _, shard := range shards {
+		if err := combined.Merge(shard.root); err != nil {
+			...
+		}
+	}
+
+The TotalElapsed and CalledTimes of other are added to t, and MaxElapsed becomes the larger of both. The children of other are merged into the children of t that have the same name; a child that t doesn't have is created under t, in the registry of t. Other statistics, such as histograms, percentiles and the standard deviation, aren't merged. Merge fails when a child must be created but its name is already taken elsewhere in the registry of t; the timers that were merged up to then keep their merged statistics.
+*/
+func (t *Timer) Merge(other *Timer) error {
+	s := other.Snapshot()
+
+	reg := t.registry()
+	reg.mu.Lock()
+	defer reg.mu.Unlock()
+
+	return t.merge(s)
+}
+
+// merge implements Merge(). The registry of t must be locked.
+func (t *Timer) merge(s TimerSnapshot) error {
+	t.mu.Lock()
+	t.TotalElapsed += s.TotalElapsed
+	t.CalledTimes += s.CalledTimes
+	t.MaxElapsed = max(t.MaxElapsed, s.MaxElapsed)
+	t.mu.Unlock()
+
+	for _, sc := range s.Children {
+		c, err := t.mergeChild(sc.Name)
+		if err != nil {
+			return err
+		}
+		if err := c.merge(sc); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// mergeChild returns the child of t with the passed-in name, which is created when it doesn't exist. The registry of t must be locked.
+func (t *Timer) mergeChild(name string) (*Timer, error) {
+	for _, c := range t.Children {
+		if c.Name == name {
+			return c, nil
+		}
+	}
+	if t.reg == nil {
+		// Unregistered timers, such as archived copies, get unregistered children.
+		c := &Timer{Name: name, Children: []*Timer{}, Parent: t}
+		t.Children = append(t.Children, c)
+		return c, nil
+	}
+	c, err := t.reg.newTimer(name, t)
+	if err != nil {
+		return nil, fmt.Errorf("can't merge into %q: %v", t.Name, err)
+	}
+	return c, nil
+}
@@ -0,0 +1,51 @@
+package calltimer
+
+import (
+	"bytes"
+	"testing"
+	"time"
+)
+
+func TestMerge(t *testing.T) {
+	resetGlobals()
+	defer func() { OutputFormat = Table }()
+
+	var shards []*Timer
+	for i := 1; i <= 2; i++ {
+		reg := NewRegistry()
+		r := reg.MustNew("work", nil)
+		r.LogDuration(time.Duration(i) * time.Second)
+		reg.MustNew("a", r).LogDuration(time.Duration(i) * time.Millisecond)
+		if i == 2 {
+			reg.MustNew("b", r).LogDuration(time.Millisecond)
+		}
+		shards = append(shards, r)
+	}
+
+	all := MustNew("work", nil)
+	for _, s := range shards {
+		if err := all.Merge(s); err != nil {
+			t.Fatalf("Merge() = %v, want nil", err)
+		}
+	}
+	OutputFormat = CSV
+	var b bytes.Buffer
+	ReportAll(&b)
+	want := "Timer;Total;Calls;Average\nwork;3s;2;1.5s\na;3ms;2;1.5ms\nb;1ms;1;1ms\n"
+	if b.String() != want {
+		t.Errorf("ReportAll() after Merge() = %q, want %q", b.String(), want)
+	}
+	if all.MaxElapsed != 2*time.Second {
+		t.Errorf("MaxElapsed after Merge() = %v, want 2s", all.MaxElapsed)
+	}
+
+	other := MustNew("other", nil)
+	MustNew("c", other)
+	reg := NewRegistry()
+	if err := reg.MustNew("x", nil).Merge(other); err != nil {
+		t.Errorf("Merge() into another registry = %v, want nil", err)
+	}
+	if err := all.Merge(other); err == nil {
+		t.Error("Merge() of a child whose name is taken succeeded, want error")
+	}
+}
@@ -0,0 +1,103 @@
+package calltimer
+
+import (
+	"errors"
+	"slices"
+	"time"
+)
+
+/*
+Option configures an optional feature of a Timer that is created using NewWith() or MustNewWith().
+*/
+type Option func(*Timer) error
+
+/*
+WithSampling records only every n-th invocation of the timer. A recorded invocation counts for n calls and for n times its duration, so that totals and averages remain comparable to those of timers that aren't sampled.
+*/
+func WithSampling(n int) Option {
+	return func(t *Timer) error {
+		if n < 1 {
+			return errors.New("sampling rate must be at least 1")
+		}
+		t.sampling = n
+		return nil
+	}
+}
+
+/*
+WithHistogram counts invocations per duration bucket. The buckets are the ascending upper bounds of each bucket; durations above the last bound are counted in an overflow bucket. The counts are available using Histogram().
+*/
+func WithHistogram(buckets ...time.Duration) Option {
+	return func(t *Timer) error {
+		if len(buckets) == 0 {
+			return errors.New("histogram needs at least one bucket")
+		}
+		for i := 1; i < len(buckets); i++ {
+			if buckets[i] <= buckets[i-1] {
+				return errors.New("histogram buckets must be ascending")
+			}
+		}
+		t.buckets = slices.Clone(buckets)
+		t.bucketCounts = make([]int, len(buckets)+1)
+		return nil
+	}
+}
+
+/*
+WithBudget sets the acceptable average duration of the timer, see OverBudget().
+*/
+func WithBudget(d time.Duration) Option {
+	return func(t *Timer) error {
+		if d <= 0 {
+			return errors.New("budget must be positive")
+		}
+		t.Budget = d
+		return nil
+	}
+}
+
+/*
+WithTags attaches free-form labels to the timer, available as the timer's Tags.
+*/
+func WithTags(tags ...string) Option {
+	return func(t *Timer) error {
+		t.Tags = append(t.Tags, tags...)
+		return nil
+	}
+}
+
+/*
+WithClock sets the time source of the timer, see SetClock().
+*/
+func WithClock(clock Clock) Option {
+	return func(t *Timer) error {
+		t.clock = clock
+		return nil
+	}
+}
+
+/*
+Histogram returns the bucket bounds as passed to WithHistogram() and the number of invocations per bucket. The counts have one more entry than the bounds: the last one holds the invocations that exceeded the last bound. Both are nil when the timer doesn't have a histogram.
+*/
+func (t *Timer) Histogram() (bounds []time.Duration, counts []int) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	return slices.Clone(t.buckets), slices.Clone(t.bucketCounts)
+}
+
+/*
+OverBudget returns true when the timer has a budget and its average duration exceeds it.
+*/
+func (t *Timer) OverBudget() bool {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	return t.Budget > 0 && average(t.TotalElapsed, t.CalledTimes) > t.Budget
+}
+
+// bucket returns the index of the histogram bucket for d.
+func (t *Timer) bucket(d time.Duration) int {
+	i, _ := slices.BinarySearch(t.buckets, d)
+	return i
+}
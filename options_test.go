@@ -0,0 +1,55 @@
+package calltimer
+
+import (
+	"slices"
+	"testing"
+	"time"
+)
+
+func TestNewWith(t *testing.T) {
+	resetGlobals()
+
+	tm := MustNewWith("options", nil,
+		WithSampling(2),
+		WithHistogram(time.Millisecond, time.Second),
+		WithBudget(time.Millisecond),
+		WithTags("db", "slow"))
+
+	for _, d := range []time.Duration{time.Microsecond, time.Microsecond, time.Minute, time.Minute} {
+		tm.LogDuration(d)
+	}
+	if tm.CalledTimes != 4 {
+		t.Errorf("CalledTimes = %v, want 4", tm.CalledTimes)
+	}
+	if want := 2*time.Microsecond + 2*time.Minute; tm.TotalElapsed != want {
+		t.Errorf("TotalElapsed = %v, want %v", tm.TotalElapsed, want)
+	}
+	if _, counts := tm.Histogram(); !slices.Equal(counts, []int{2, 0, 2}) {
+		t.Errorf("Histogram() counts = %v, want [2 0 2]", counts)
+	}
+	if !tm.OverBudget() {
+		t.Error("OverBudget() = false, want true")
+	}
+	if !slices.Equal(tm.Tags, []string{"db", "slow"}) {
+		t.Errorf("Tags = %v, want [db slow]", tm.Tags)
+	}
+}
+
+func TestNewWithErrors(t *testing.T) {
+	resetGlobals()
+
+	for _, opt := range []Option{
+		WithSampling(0),
+		WithHistogram(),
+		WithHistogram(time.Second, time.Millisecond),
+		WithBudget(0),
+	} {
+		if _, err := NewWith("bad", nil, opt); err == nil {
+			t.Error("NewWith() with bad option succeeded, want error")
+		}
+	}
+	// Failed creations don't register the name.
+	if _, err := New("bad", nil); err != nil {
+		t.Errorf("New(bad) = %v, want nil error", err)
+	}
+}
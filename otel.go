@@ -0,0 +1,75 @@
+package calltimer
+
+import (
+	"context"
+	"time"
+)
+
+/*
+SpanTracer starts spans in a tracing system, see ExportOTel(). StartSpan starts a span with the passed-in name and start time, which carries the number of calls of the timer, e.g. as an attribute. The returned context holds the span, so that spans that are started using it become its children. The returned function ends the span at the passed-in time.
+
+This package doesn't depend on OpenTelemetry. An adapter for an OpenTelemetry trace.Tracer is:
+
+	type otelTracer struct{ trace.Tracer }
+
+	func (t otelTracer) StartSpan(ctx context.Context, name string, start time.Time, calls int) (context.Context, func(time.Time)) {
+		ctx, span := t.Start(ctx, name, trace.WithTimestamp(start), trace.WithAttributes(attribute.Int("calltimer.calls", calls)))
+		return ctx, func(end time.Time) { span.End(trace.WithTimestamp(end)) }
+	}
+*/
+type SpanTracer interface {
+	StartSpan(ctx context.Context, name string, start time.Time, calls int) (context.Context, func(end time.Time))
+}
+
+/*
+ExportOTel emits a span per timer with activity to tracer, with the spans of children nested under the spans of their parents:
+
+	calltimer.ExportOTel(ctx, otelTracer{otel.Tracer("calltimer")})
+
+Since timers only hold totals, the spans are synthesized: each timer is a single span with its TotalElapsed as duration, laid out like WriteChromeTrace() does. The spans share a base timestamp, chosen so that the last root ends at the time of the export.
+*/
+func ExportOTel(ctx context.Context, tracer SpanTracer) {
+	defaultRegistry.ExportOTel(ctx, tracer)
+}
+
+/*
+ExportOTel is like the package-level ExportOTel(), but exports the timers of this registry.
+*/
+func (r *Registry) ExportOTel(ctx context.Context, tracer SpanTracer) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	rows := reportRows(r.roots, (*Timer).hasOwnActivity)
+	starts := spanStarts(rows)
+	base := DefaultClock.Now()
+	for _, row := range rows {
+		if row.Depth == 0 {
+			base = base.Add(-row.Total)
+		}
+	}
+
+	type open struct {
+		ctx context.Context
+		end func(time.Time)
+		at  time.Time
+	}
+	var stack []open // Per depth, the span of the current ancestor
+	endUntil := func(depth int) {
+		for len(stack) > depth {
+			o := stack[len(stack)-1]
+			o.end(o.at)
+			stack = stack[:len(stack)-1]
+		}
+	}
+	for i, row := range rows {
+		endUntil(row.Depth)
+		parent := ctx
+		if row.Depth > 0 {
+			parent = stack[row.Depth-1].ctx
+		}
+		start := base.Add(starts[i])
+		sctx, end := tracer.StartSpan(parent, row.Name, start, row.Calls)
+		stack = append(stack, open{ctx: sctx, end: end, at: start.Add(row.Total)})
+	}
+	endUntil(0)
+}
@@ -0,0 +1,50 @@
+package calltimer
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+)
+
+type spanKey struct{}
+
+// fakeTracer records spans as "parent>name start-end calls", with times in seconds after the Unix epoch.
+type fakeTracer struct {
+	spans []string
+}
+
+func (f *fakeTracer) StartSpan(ctx context.Context, name string, start time.Time, calls int) (context.Context, func(time.Time)) {
+	parent, _ := ctx.Value(spanKey{}).(string)
+	return context.WithValue(ctx, spanKey{}, name), func(end time.Time) {
+		f.spans = append(f.spans, fmt.Sprintf("%s>%s %d-%d %d", parent, name, start.Unix(), end.Unix(), calls))
+	}
+}
+
+func TestExportOTel(t *testing.T) {
+	resetGlobals()
+	defer func() { DefaultClock = realClock{} }()
+	DefaultClock = NewFakeClock(time.Unix(100, 0))
+
+	r := MustNew("root", nil)
+	a := MustNew("a", r)
+	MustNew("b", a).LogDuration(time.Second)
+	MustNew("c", r).LogDuration(2 * time.Second)
+	a.LogDuration(3 * time.Second)
+	r.LogDuration(10 * time.Second)
+	r.LogDuration(10 * time.Second)
+	MustNew("other", nil).LogDuration(5 * time.Second)
+
+	var f fakeTracer
+	ExportOTel(context.Background(), &f)
+	want := []string{
+		"a>b 75-76 1",
+		"root>a 75-78 1",
+		"root>c 78-80 1",
+		">root 75-95 2",
+		">other 95-100 1",
+	}
+	if fmt.Sprint(f.spans) != fmt.Sprint(want) {
+		t.Errorf("ExportOTel() spans = %q, want %q", f.spans, want)
+	}
+}
@@ -0,0 +1,68 @@
+package calltimer
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"strings"
+)
+
+/*
+WritePrometheus writes all timers in the Prometheus text exposition format, so that they can be scraped from a metrics endpoint without a dependency on the Prometheus client library:
+
+	http.HandleFunc("/metrics", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/plain; version=0.0.4")
+		calltimer.WritePrometheus(w)
+	})
+
+Each timer is exposed as the counters calltimer_seconds_total and calltimer_calls_total, labeled with the timer's name and the name of its parent (empty for roots). The statistics of each timer are copied under its lock, so this is safe while other goroutines are logging.
+*/
+func WritePrometheus(w io.Writer) error {
+	return defaultRegistry.WritePrometheus(w)
+}
+
+/*
+WritePrometheus is like the package-level WritePrometheus(), but writes the timers of this registry.
+*/
+func (r *Registry) WritePrometheus(w io.Writer) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	var stats []*Timer
+	var walk func(ts []*Timer)
+	walk = func(ts []*Timer) {
+		for _, t := range ts {
+			stats = append(stats, t.copyStats())
+			walk(t.Children)
+		}
+	}
+	walk(r.roots)
+
+	bw := bufio.NewWriter(w)
+	for _, m := range []struct {
+		name, help string
+		value      func(s *Timer) string
+	}{
+		{"calltimer_seconds_total", "Total time spent in the timer.", func(s *Timer) string {
+			return fmt.Sprint(s.TotalElapsed.Seconds())
+		}},
+		{"calltimer_calls_total", "Number of calls of the timer.", func(s *Timer) string {
+			return fmt.Sprint(s.CalledTimes)
+		}},
+	} {
+		fmt.Fprintf(bw, "# HELP %s %s\n# TYPE %s counter\n", m.name, m.help, m.name)
+		for _, s := range stats {
+			var parent string
+			if s.Parent != nil {
+				parent = s.Parent.Name
+			}
+			fmt.Fprintf(bw, "%s{timer=\"%s\",parent=\"%s\"} %s\n", m.name, promLabel(s.Name), promLabel(parent), m.value(s))
+		}
+	}
+	return bw.Flush()
+}
+
+// promLabel escapes a label value for the Prometheus text format.
+func promLabel(s string) string {
+	return strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`).Replace(s)
+}
@@ -0,0 +1,34 @@
+package calltimer
+
+import (
+	"bytes"
+	"testing"
+	"time"
+)
+
+func TestWritePrometheus(t *testing.T) {
+	resetGlobals()
+
+	r := MustNew("root", nil)
+	c := MustNew(`a "quoted" child`, r)
+	r.LogDuration(1500 * time.Millisecond)
+	c.LogDuration(time.Second)
+	c.LogDuration(time.Second)
+
+	var b bytes.Buffer
+	if err := WritePrometheus(&b); err != nil {
+		t.Fatalf("WritePrometheus() = %v", err)
+	}
+	want := `# HELP calltimer_seconds_total Total time spent in the timer.
+# TYPE calltimer_seconds_total counter
+calltimer_seconds_total{timer="root",parent=""} 1.5
+calltimer_seconds_total{timer="a \"quoted\" child",parent="root"} 2
+# HELP calltimer_calls_total Number of calls of the timer.
+# TYPE calltimer_calls_total counter
+calltimer_calls_total{timer="root",parent=""} 1
+calltimer_calls_total{timer="a \"quoted\" child",parent="root"} 2
+`
+	if b.String() != want {
+		t.Errorf("WritePrometheus() = %q, want %q", b.String(), want)
+	}
+}
@@ -0,0 +1,238 @@
+package calltimer
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"slices"
+	"sync"
+	"sync/atomic"
+)
+
+/*
+Registry holds a set of timers with unique names. The package-level functions such as New() and ReportAll() use a default registry. A separate registry allows e.g. a library to instrument its code without sharing the namespace of timer names with the program that uses it:
+
+	var (
+		reg        = calltimer.NewRegistry()
+		queryTimer = reg.MustNew("query", nil)
+	)
+
+	func DumpTimers(w io.Writer) error {
+		return reg.ReportAll(w)
+	}
+
+A timer's parent must be in the same registry as the timer.
+
+Locking follows a fixed hierarchy: a registry's lock protects its timer names, its roots and the parent/child links of its timers, and each timer's own lock protects its statistics. When both are needed, the registry is locked first; code that holds a timer's lock never takes a registry lock. Reports lock the registry, copy the statistics of each timer under the timer's lock, and format the copies. This makes it safe to report, e.g. using ReportAll() and Report() from different goroutines, while timers are being created and logged.
+*/
+type Registry struct {
+	mu       sync.Mutex            // Lock for the timers and the tree, taken before any timer's lock
+	timers   map[string]*Timer     // Map of timers to avoid duplicate names
+	roots    []*Timer              // List of roots to ReportAll()
+	clock    atomic.Pointer[Clock] // Time source of the timers, nil for DefaultClock, see SetClock()
+	archives map[string]*Timer     // Forests that were stored using Archive(), keyed by label
+}
+
+// defaultRegistry is used by the package-level functions.
+var defaultRegistry = NewRegistry()
+
+/*
+NewRegistry returns an empty registry.
+*/
+func NewRegistry() *Registry {
+	return &Registry{timers: map[string]*Timer{}, roots: []*Timer{}, archives: map[string]*Timer{}}
+}
+
+/*
+New is like the package-level New(), but creates the timer in this registry.
+*/
+func (r *Registry) New(name string, parent *Timer) (*Timer, error) {
+	return r.NewWith(name, parent)
+}
+
+/*
+NewWith is like the package-level NewWith(), but creates the timer in this registry.
+*/
+func (r *Registry) NewWith(name string, parent *Timer, opts ...Option) (*Timer, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	return r.newTimer(name, parent, opts...)
+}
+
+/*
+MustNew wraps New and panics upon error.
+*/
+func (r *Registry) MustNew(name string, parent *Timer) *Timer {
+	return r.MustNewWith(name, parent)
+}
+
+/*
+MustNewWith wraps NewWith and panics upon error.
+*/
+func (r *Registry) MustNewWith(name string, parent *Timer, opts ...Option) *Timer {
+	t, err := r.NewWith(name, parent, opts...)
+	if err != nil {
+		panic(fmt.Sprintf("TIMER PANIC: %v", err))
+	}
+	return t
+}
+
+/*
+NewUnder is like the package-level NewUnder(), but creates the timer in this registry.
+*/
+func (r *Registry) NewUnder(name, parentName string) (*Timer, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	parent, ok := r.timers[parentName]
+	if !ok {
+		return nil, fmt.Errorf("timer %q: parent %q is not defined", name, parentName)
+	}
+	return r.newTimer(name, parent)
+}
+
+/*
+MustNewUnder wraps NewUnder and panics upon error.
+*/
+func (r *Registry) MustNewUnder(name, parentName string) *Timer {
+	t, err := r.NewUnder(name, parentName)
+	if err != nil {
+		panic(fmt.Sprintf("TIMER PANIC: %v", err))
+	}
+	return t
+}
+
+// newTimer creates and registers a timer. The registry must be locked.
+func (r *Registry) newTimer(name string, parent *Timer, opts ...Option) (*Timer, error) {
+	// Name must exist and can't be redefined
+	_, ok := r.timers[name]
+	if name == "" {
+		return nil, errors.New("can't create a timer without a name")
+	}
+	if ok {
+		return nil, fmt.Errorf("timer %q is already defined", name)
+	}
+	if parent != nil && (parent.reg != r || r.timers[parent.Name] != parent) {
+		return nil, fmt.Errorf("timer %q: parent %q is in a different registry", name, parent.Name)
+	}
+
+	t := &Timer{Name: name, Children: []*Timer{}, Parent: parent, reg: r}
+	for _, opt := range opts {
+		if err := opt(t); err != nil {
+			return nil, fmt.Errorf("timer %q: %v", name, err)
+		}
+	}
+	t.disabled = !t.enabledByPatterns()
+	if parent != nil {
+		parent.mu.Lock()
+		t.paused = parent.paused
+		parent.mu.Unlock()
+	}
+	r.timers[name] = t
+	if parent == nil {
+		r.roots = append(r.roots, t)
+	} else {
+		parent.Children = append(parent.Children, t)
+	}
+	return t, nil
+}
+
+/*
+Remove is like the package-level Remove(), but removes the timer from this registry.
+*/
+func (r *Registry) Remove(name string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	t, ok := r.timers[name]
+	if !ok {
+		return fmt.Errorf("timer %q is not defined", name)
+	}
+	if len(t.Children) > 0 {
+		return fmt.Errorf("timer %q still has children", name)
+	}
+	if t.Parent == nil {
+		r.roots = slices.DeleteFunc(r.roots, func(c *Timer) bool { return c == t })
+	} else {
+		t.Parent.Children = slices.DeleteFunc(t.Parent.Children, func(c *Timer) bool { return c == t })
+	}
+	delete(r.timers, name)
+	return nil
+}
+
+/*
+SetClock sets the time source of the timers in this registry that have no clock of their own (see Timer.SetClock()). Passing nil restores DefaultClock. This allows e.g. a test to drive the timers of a registry by a FakeClock, without affecting other registries.
+*/
+func (r *Registry) SetClock(c Clock) {
+	if c == nil {
+		r.clock.Store(nil)
+		return
+	}
+	r.clock.Store(&c)
+}
+
+/*
+Names is like the package-level Names(), but lists the timers of this registry.
+*/
+func (r *Registry) Names() []string {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	names := make([]string, 0, len(r.timers))
+	for name := range r.timers {
+		names = append(names, name)
+	}
+	slices.Sort(names)
+	return names
+}
+
+/*
+Get is like the package-level Get(), but looks up the timer in this registry.
+*/
+func (r *Registry) Get(name string) (*Timer, bool) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	t, ok := r.timers[name]
+	return t, ok
+}
+
+// mustReuse returns the timer with the passed-in name, which is created under parent when it doesn't exist yet. It panics when the timer can't be created, like MustNew.
+func (r *Registry) mustReuse(name string, parent *Timer) *Timer {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if t, ok := r.timers[name]; ok {
+		return t
+	}
+	t, err := r.newTimer(name, parent)
+	if err != nil {
+		panic(fmt.Sprintf("TIMER PANIC: %v", err))
+	}
+	return t
+}
+
+/*
+ReportAll is like the package-level ReportAll(), but reports the root timers of this registry.
+*/
+func (r *Registry) ReportAll(wr io.Writer) error {
+	if !Active {
+		return nil
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	w, flush := reportWriter(wr)
+	s := newTextSink(w, wr)
+	sendReport(s, r.roots, (*Timer).hasOwnActivity)
+	return s.finish(flush)
+}
+
+// registry returns the registry whose lock protects the timer's place in the tree. Unregistered timers, such as archived copies (see Archive()) and hand-assembled trees, fall back to the default registry.
+func (t *Timer) registry() *Registry {
+	if t.reg == nil {
+		return defaultRegistry
+	}
+	return t.reg
+}
@@ -0,0 +1,160 @@
+package calltimer
+
+import (
+	"bytes"
+	"net/http/httptest"
+	"regexp"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestRegistry(t *testing.T) {
+	resetGlobals()
+
+	reg := NewRegistry()
+	r := reg.MustNew("shared", nil)
+	c := reg.MustNew("child", r)
+	if _, err := New("shared", nil); err != nil {
+		t.Errorf("New() of a name in another registry = %v, want no error", err)
+	}
+	if _, err := reg.New("shared", nil); err == nil {
+		t.Error("Registry.New() of a duplicate name succeeded, want error")
+	}
+	if _, err := New("stray", c); err == nil {
+		t.Error("New() under a parent in another registry succeeded, want error")
+	}
+
+	r.LogDuration(time.Second)
+	c.LogDuration(time.Second)
+	var b bytes.Buffer
+	reg.ReportAll(&b)
+	if !strings.Contains(b.String(), "child") {
+		t.Errorf("Registry.ReportAll() = %q, want child", b.String())
+	}
+	b.Reset()
+	ReportAll(&b)
+	if strings.Contains(b.String(), "child") {
+		t.Errorf("ReportAll() = %q, want no timers of the other registry", b.String())
+	}
+}
+
+func TestRemove(t *testing.T) {
+	resetGlobals()
+
+	r := MustNew("root", nil)
+	MustNew("job", r)
+	if err := Remove("root"); err == nil {
+		t.Error("Remove() of a timer with children succeeded, want error")
+	}
+	if err := Remove("job"); err != nil {
+		t.Errorf("Remove() = %v, want nil", err)
+	}
+	if len(r.Children) != 0 {
+		t.Errorf("after Remove(): root has children %v, want none", r.Children)
+	}
+	if err := Remove("job"); err == nil {
+		t.Error("Remove() of an unknown timer succeeded, want error")
+	}
+	if _, err := New("job", r); err != nil {
+		t.Errorf("New() of a removed name = %v, want nil", err)
+	}
+	Remove("job")
+	if err := Remove("root"); err != nil || len(defaultRegistry.roots) != 0 {
+		t.Errorf("Remove() of a root = %v, roots %v, want no error and no roots", err, defaultRegistry.roots)
+	}
+}
+
+func TestNamesAndGet(t *testing.T) {
+	resetGlobals()
+
+	r := MustNew("root", nil)
+	c := MustNew("child", r)
+	NewRegistry().MustNew("elsewhere", nil)
+	if got, want := Names(), []string{"child", "root"}; !slices.Equal(got, want) {
+		t.Errorf("Names() = %q, want %q", got, want)
+	}
+	if got, ok := Get("child"); !ok || got != c {
+		t.Errorf("Get(%q) = %v, %v, want the child timer", "child", got, ok)
+	}
+	if got, ok := Get("elsewhere"); ok || got != nil {
+		t.Errorf("Get(%q) = %v, %v, want nil, false", "elsewhere", got, ok)
+	}
+}
+
+func TestRegistryMethods(t *testing.T) {
+	resetGlobals()
+
+	MustNew("global", nil).LogDuration(time.Second)
+	reg := NewRegistry()
+	r := reg.MustNew("local", nil)
+	reg.MustNew("Bad Name", r).LogDuration(time.Second)
+	r.LogDuration(2 * time.Second)
+
+	var walked []string
+	reg.Walk(func(t *Timer, _ TimerStats, _ int) bool {
+		walked = append(walked, t.Name)
+		return true
+	})
+	if want := []string{"local", "Bad Name"}; !slices.Equal(walked, want) {
+		t.Errorf("Registry.Walk() visited %q, want %q", walked, want)
+	}
+	if got := reg.FilterTimers(func(*Timer, TimerStats) bool { return true }); len(got) != 2 {
+		t.Errorf("Registry.FilterTimers() = %v, want the 2 timers of the registry", got)
+	}
+	if st := reg.Stats(); st.Timers != 2 || st.Roots != 1 {
+		t.Errorf("Registry.Stats() = %+v, want 2 timers and 1 root", st)
+	}
+	if errs := reg.ValidateNames(regexp.MustCompile(`^[a-z]+$`)); len(errs) != 1 {
+		t.Errorf("Registry.ValidateNames() = %v, want 1 error", errs)
+	}
+
+	for name, write := range map[string]func(*bytes.Buffer) error{
+		"WriteDOT":         func(b *bytes.Buffer) error { return reg.WriteDOT(b) },
+		"WriteFlat":        func(b *bytes.Buffer) error { return reg.WriteFlat(b) },
+		"WriteChromeTrace": func(b *bytes.Buffer) error { return reg.WriteChromeTrace(b) },
+		"WritePrometheus":  func(b *bytes.Buffer) error { return reg.WritePrometheus(b) },
+		"ExportBinary":     func(b *bytes.Buffer) error { return reg.ExportBinary(b) },
+		"Handler": func(b *bytes.Buffer) error {
+			rec := httptest.NewRecorder()
+			reg.Handler().ServeHTTP(rec, httptest.NewRequest("GET", "/", nil))
+			b.Write(rec.Body.Bytes())
+			return nil
+		},
+		"Interactive": func(b *bytes.Buffer) error {
+			reg.Interactive(strings.NewReader("list\nshow local\n"), b)
+			return nil
+		},
+	} {
+		var b bytes.Buffer
+		if err := write(&b); err != nil {
+			t.Errorf("Registry.%s() = %v, want no error", name, err)
+		}
+		if !strings.Contains(b.String(), "local") || strings.Contains(b.String(), "global") {
+			t.Errorf("Registry.%s() = %q, want only the timers of the registry", name, b.String())
+		}
+	}
+
+	sink := &recordingSink{}
+	reg.ReportAllTo(sink)
+	if len(sink.rows) != 2 || sink.rows[0].Timer != r {
+		t.Errorf("Registry.ReportAllTo() rows = %v, want the 2 timers of the registry", sink.rows)
+	}
+
+	reg.Archive("before")
+	if _, ok := reg.Archives()["before"]; !ok {
+		t.Error("Registry.Archives() lacks the archive of the registry")
+	}
+	if _, ok := Archives()["before"]; ok {
+		t.Error("Archives() holds an archive of another registry")
+	}
+
+	reg.ResetAll()
+	if s := r.Snapshot(); s.CalledTimes != 0 {
+		t.Errorf("after Registry.ResetAll(), CalledTimes = %d, want 0", s.CalledTimes)
+	}
+	if g, _ := Get("global"); g.Snapshot().CalledTimes != 1 {
+		t.Error("Registry.ResetAll() reset a timer of the default registry")
+	}
+}
@@ -0,0 +1,858 @@
+package calltimer
+
+import (
+	"bufio"
+	"cmp"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+// String lengths over all roots
+type reportLen struct {
+	leaderLen int   // String length of indentation + name
+	totalLen  int   // String length of total duration
+	callsLen  int   // String length of # of calls
+	avgLen    int   // String length of average duration
+	extraLens []int // String lengths of optional columns
+}
+
+/*
+ReportAll sends reports of all root timers (i.e., those which don't have a parent) to the passed-in io.Writer.
+
+Example:
+
+	r1 := calltimer.MustNew("r1", nil)
+	c1 := calltimer.MustNew("c1", r1)
+	r2 := calltimer.MustNew("r2", nil)
+
+	// This reports on root timer "r1" together with its child timer "c1",
+	// and on the other root timer "r2". Root timers without activity are
+	// not reported.
+	calltimer.ReportAll(os.Stdout)
+
+When no timer has activity, the report is empty, unless ReportEmptyMessage is set. ReportAll may be called while other goroutines are still logging: the statistics of each timer are copied under the timer's lock.
+
+The returned error is the first error of writing to wr, e.g. when the far end of a socket was closed. The rest of the report is then skipped. Formatting errors, such as an invalid CSVDelimiter, are returned too.
+*/
+func ReportAll(wr io.Writer) error {
+	return defaultRegistry.ReportAll(wr)
+}
+
+/*
+ReportAllWithWallClock is like ReportAll, but adds the column "% of wall": the share of TotalElapsed in an externally measured wall-clock window, e.g. the runtime of the program. Unlike the shares of ShowPercentages, this shows how much of the window was actually instrumented, so it's only shown for the root timers:
+
+	start := time.Now()
+	run()
+	calltimer.ReportAllWithWallClock(os.Stdout, time.Since(start))
+
+Timers that run in parallel may exceed 100%.
+*/
+func ReportAllWithWallClock(wr io.Writer, wall time.Duration) error {
+	if !Active {
+		return nil
+	}
+	defaultRegistry.mu.Lock()
+	defer defaultRegistry.mu.Unlock()
+
+	w, flush := reportWriter(wr)
+	s := newTextSink(w, wr)
+	s.wall = wall
+	sendReport(s, defaultRegistry.roots, (*Timer).hasOwnActivity)
+	return s.finish(flush)
+}
+
+/*
+ReportChanged is like ReportAll, but only reports the timers that were called since the previous invocation of ReportChanged, together with their parents for context. The first invocation reports all timers that were called at all. This keeps periodic reports focused on what's currently happening.
+*/
+func ReportChanged(wr io.Writer) error {
+	if !Active {
+		return nil
+	}
+	defaultRegistry.mu.Lock()
+	defer defaultRegistry.mu.Unlock()
+
+	w, flush := reportWriter(wr)
+	s := newTextSink(w, wr)
+	// The copies of the statistics are reported, so their calls are the ones to mark as reported. Calls that are logged meanwhile are left for the next invocation.
+	reported := map[string]int{}
+	sendReport(s, defaultRegistry.roots, func(t *Timer) bool {
+		reported[t.Name] = t.CalledTimes
+		return t.CalledTimes > t.reportedCalls
+	})
+
+	var mark func(ts []*Timer)
+	mark = func(ts []*Timer) {
+		for _, t := range ts {
+			if calls, ok := reported[t.Name]; ok {
+				t.mu.Lock()
+				t.reportedCalls = calls
+				t.mu.Unlock()
+			}
+			mark(t.Children)
+		}
+	}
+	mark(defaultRegistry.roots)
+	return s.finish(flush)
+}
+
+/*
+ReportAllAbove is like ReportAll, but only reports the timers whose TotalElapsed is at least min, together with their parents for context. This hides the noise of trivially fast timers, while an expensive timer is still shown under a fast parent.
+*/
+func ReportAllAbove(wr io.Writer, min time.Duration) error {
+	if !Active {
+		return nil
+	}
+	defaultRegistry.mu.Lock()
+	defer defaultRegistry.mu.Unlock()
+
+	w, flush := reportWriter(wr)
+	s := newTextSink(w, wr)
+	sendReport(s, defaultRegistry.roots, above(min))
+	return s.finish(flush)
+}
+
+/*
+ReportAbove is like Report, but only reports the timers whose TotalElapsed is at least min, see ReportAllAbove.
+*/
+func (t *Timer) ReportAbove(wr io.Writer, min time.Duration) error {
+	if !Active {
+		return nil
+	}
+	reg := t.registry()
+	reg.mu.Lock()
+	defer reg.mu.Unlock()
+
+	w, flush := reportWriter(wr)
+	s := newTextSink(w, wr)
+	sendReport(s, []*Timer{t}, above(min))
+	return s.finish(flush)
+}
+
+// above returns a filter for reportRows that includes timers with activity of at least min.
+func above(min time.Duration) func(*Timer) bool {
+	return func(t *Timer) bool {
+		return t.hasOwnActivity() && t.TotalElapsed >= min
+	}
+}
+
+/*
+Report sends a report for the applicable timer to the passed-in io.Writer. For example:
+
+		main        total 326.627542ms in  1 calls, avg 326.627542ms
+	  	  outer     total  326.62675ms in  2 calls, avg 163.313375ms
+	        middle  total 260.368249ms in  6 calls, avg  43.394708ms
+	          inner total 260.350961ms in 24 calls, avg  10.847956ms
+
+In this case, there is a one-to-one parent/child relationship: main has one child outer, which has one child middle, which has one child inner.
+
+Timers that have no logged activity are not reported; see also ReportEmptyMessage. The returned error is handled as in ReportAll.
+*/
+func (t *Timer) Report(wr io.Writer) error {
+	if !Active {
+		return nil
+	}
+	reg := t.registry()
+	reg.mu.Lock()
+	defer reg.mu.Unlock()
+
+	w, flush := reportWriter(wr)
+	s := newTextSink(w, wr)
+	sendReport(s, []*Timer{t}, (*Timer).hasOwnActivity)
+	return s.finish(flush)
+}
+
+/*
+ReportRoots is like ReportAll, but reports only the passed-in timers, in the passed-in order, each as the root of its part of the report. This allows to select and order what's reported, without changing the timer tree. SortBy still orders the children of each timer. The column widths are shared across all parts, as in ReportAll. The timers must be in the same registry.
+*/
+func ReportRoots(wr io.Writer, roots ...*Timer) error {
+	if !Active {
+		return nil
+	}
+	reg := defaultRegistry
+	for i, r := range roots {
+		if i == 0 {
+			reg = r.registry()
+		} else if r.registry() != reg {
+			return fmt.Errorf("timer %q is in a different registry than %q", r.Name, roots[0].Name)
+		}
+	}
+	reg.mu.Lock()
+	defer reg.mu.Unlock()
+
+	w, flush := reportWriter(wr)
+	s := newTextSink(w, wr)
+	s.Begin()
+	for _, r := range roots {
+		// One at a time, so that SortBy doesn't reorder the roots.
+		walkRows([]*Timer{r}, (*Timer).hasOwnActivity, s.Row)
+	}
+	s.End()
+	return s.finish(flush)
+}
+
+/*
+ReportSubtrees is the same as ReportRoots: it reports the passed-in timers, which may be anywhere in the tree, with their descendants. The columns are aligned across all subtrees, unlike consecutive reports using Report(), so that e.g. two branches can be compared side by side.
+*/
+func ReportSubtrees(wr io.Writer, timers ...*Timer) error {
+	return ReportRoots(wr, timers...)
+}
+
+/*
+ReportAllString is like ReportAll, but returns the report as a string, e.g. to embed it in a log message.
+*/
+func ReportAllString() string {
+	var b strings.Builder
+	ReportAll(&b)
+	return b.String()
+}
+
+/*
+ReportString is like Report, but returns the report as a string.
+*/
+func (t *Timer) ReportString() string {
+	var b strings.Builder
+	t.Report(&b)
+	return b.String()
+}
+
+/*
+ColumnWidths returns the widths that Table and PlainText reports use to align the columns of the passed-in timers and their descendants, or of all root timers when none are passed: the indented name, the total, the number of calls and the average. This allows custom formats, e.g. using Walk(), to align like the built-in ones. The widths are those of the values; a Table additionally widens the columns to fit the header. Like in reports, names are indented per IndentUnit or TreeConnectors, timers without activity are skipped, and ReportMaxRows, ShowAverage and ShowGrandTotal apply. The timers must be in the same registry.
+*/
+func ColumnWidths(roots ...*Timer) (leader, total, calls, avg int) {
+	reg := defaultRegistry
+	if len(roots) > 0 {
+		reg = roots[0].registry()
+	}
+	reg.mu.Lock()
+	defer reg.mu.Unlock()
+
+	rows := reportRows(reg.roots, (*Timer).hasOwnActivity)
+	if len(roots) > 0 {
+		rows = nil
+		for _, r := range roots {
+			rows = append(rows, reportRows([]*Timer{r}, (*Timer).hasOwnActivity)...)
+		}
+	}
+	if len(rows) == 0 {
+		return 0, 0, 0, 0
+	}
+	all, indents := rows, treeIndents(rows)
+	if ReportMaxRows > 0 && len(rows) > ReportMaxRows {
+		rows, indents = rows[:ReportMaxRows], indents[:ReportMaxRows]
+	}
+	if ShowGrandTotal {
+		rows = append(slices.Clip(rows), grandTotal(all))
+		indents = append(slices.Clip(indents), "")
+	}
+	l := calculateLengths(rows, indents, nil, nil)
+	return l.leaderLen, l.totalLen, l.callsLen, l.avgLen
+}
+
+// reportWriter returns the writer that a report should be sent to, and a function that finishes the report and returns the first write error.
+func reportWriter(wr io.Writer) (io.Writer, func() error) {
+	if !ReportBuffered {
+		ew := &errWriter{wr: wr}
+		return ew, func() error { return ew.err }
+	}
+	bw := bufio.NewWriter(wr)
+	return bw, bw.Flush
+}
+
+// errWriter retains the first error of writing to wr, and skips writes after that.
+type errWriter struct {
+	wr  io.Writer
+	err error
+}
+
+func (ew *errWriter) Write(p []byte) (int, error) {
+	if ew.err != nil {
+		return 0, ew.err
+	}
+	var n int
+	n, ew.err = ew.wr.Write(p)
+	return n, ew.err
+}
+
+// snapshot is a timer together with a consistent copy of its statistics, so that reports don't race against concurrent logging.
+type snapshot struct {
+	t     *Timer // The original timer
+	stats *Timer // Copy of the statistics, see copyStats()
+}
+
+// snapshots returns snapshots of the passed-in timers.
+func snapshots(ts []*Timer) []snapshot {
+	sns := make([]snapshot, len(ts))
+	for i, t := range ts {
+		sns[i] = snapshot{t: t, stats: t.copyStats()}
+	}
+	return sns
+}
+
+// reportRows returns the rows to report for the passed-in roots, depth-first: the timers for which include returns true, and their ancestors. The include function receives a copy of the statistics of each timer. The registry of the timers must be locked, but the timers themselves must not be locked.
+func reportRows(ts []*Timer, include func(*Timer) bool) []ReportRow {
+	var rows []ReportRow
+	walkRows(ts, include, func(r ReportRow) { rows = append(rows, r) })
+	return rows
+}
+
+// walkRows passes the rows of reportRows to emit, in the same order. A row is passed as soon as it's known to be shown, so that only the rows of the ancestors of the current timer are held, rather than the whole report. The registry of the timers must be locked, but the timers themselves must not be locked.
+func walkRows(ts []*Timer, include func(*Timer) bool, emit func(ReportRow)) {
+	var pending [][]ReportRow // Per depth, the rows of an ancestor that aren't emitted yet, nil once they are
+	flush := func() {
+		for i, rows := range pending {
+			for _, r := range rows {
+				emit(r)
+			}
+			pending[i] = nil
+		}
+	}
+	// walk returns whether the timer is shown, and its number of rows including those of its descendants.
+	var walk func(sn snapshot, lev int, root *Timer, parentTotal, rootTotal time.Duration) (bool, int)
+	walk = func(sn snapshot, lev int, root *Timer, parentTotal, rootTotal time.Duration) (bool, int) {
+		children := snapshots(sn.t.Children)
+		r := sn.reportRow(lev, root, children)
+		if lev == 0 {
+			rootTotal = sn.stats.TotalElapsed
+		}
+		r.parentTotal, r.rootTotal = parentTotal, rootTotal
+		own := []ReportRow{r}
+		if ReportTagBreakdown {
+			own = append(own, r.tagRows()...)
+		}
+		// Below ReportMaxDepth, rows are only counted as hidden rows of their ancestor.
+		hidden := ReportMaxDepth > 0 && lev >= ReportMaxDepth
+		collapse := ReportMaxDepth > 0 && lev+1 >= ReportMaxDepth
+		if !hidden {
+			pending = append(pending[:lev], own)
+		}
+
+		shown := include(sn.stats)
+		if shown && !collapse {
+			flush()
+		}
+		n := len(own)
+		for _, c := range sorted(children) {
+			if cShown, cn := walk(c, lev+1, root, sn.stats.TotalElapsed, rootTotal); cShown {
+				shown = true
+				n += cn
+			}
+		}
+		if !shown {
+			if !hidden {
+				pending = pending[:lev]
+			}
+			return false, 0
+		}
+		if collapse && !hidden {
+			r.Hidden = n - 1
+			pending[lev] = []ReportRow{r}
+			flush()
+		}
+		return true, n
+	}
+	for _, sn := range sorted(snapshots(ts)) {
+		walk(sn, 0, sn.t, 0, 0)
+	}
+}
+
+// sorted returns the snapshots in the order of SortBy. The passed-in slice isn't changed.
+func sorted(sns []snapshot) []snapshot {
+	var cmpFunc func(a, b snapshot) int
+	switch SortBy {
+	case SortTotalDesc:
+		cmpFunc = func(a, b snapshot) int { return cmp.Compare(b.stats.TotalElapsed, a.stats.TotalElapsed) }
+	case SortCallsDesc:
+		cmpFunc = func(a, b snapshot) int { return cmp.Compare(b.stats.CalledTimes, a.stats.CalledTimes) }
+	case SortNameAsc:
+		cmpFunc = func(a, b snapshot) int { return cmp.Compare(a.stats.Name, b.stats.Name) }
+	default:
+		return sns
+	}
+	sns = slices.Clone(sns)
+	slices.SortStableFunc(sns, cmpFunc)
+	return sns
+}
+
+// reportRow returns the timer's row in a report, given the snapshots of its children.
+func (sn snapshot) reportRow(lev int, root *Timer, children []snapshot) ReportRow {
+	r := ReportRow{
+		Timer: sn.t,
+		Root:  root,
+		Depth: lev,
+		Name:  sn.stats.Name,
+		Total: sn.stats.TotalElapsed,
+		Calls: sn.stats.CalledTimes,
+		stats: sn.stats,
+	}
+	r.Self, r.Overlap = selfTime(r.Total, children)
+	if ReportExclusive {
+		r.Total = r.Self
+	}
+	r.Average = average(r.Total, r.Calls)
+	return r
+}
+
+// selfTime returns a total minus the totals of the children, which is the time that a timer spent outside of its children. The result is clamped at zero, as children that run in parallel may add up to more than the total of their parent; overlap is then true.
+func selfTime(total time.Duration, children []snapshot) (self time.Duration, overlap bool) {
+	self = total
+	for _, c := range children {
+		self -= c.stats.TotalElapsed
+	}
+	return max(self, 0), self < 0
+}
+
+// hasOwnActivity returns true when the timer itself has logged activity, regardless of its children. Calls count as activity even when they took no measurable time.
+func (t *Timer) hasOwnActivity() bool {
+	return t.CalledTimes > 0 || t.TotalElapsed > 0
+}
+
+// isTerminal returns true when wr is a terminal. It's a variable so that tests can override it.
+var isTerminal = func(wr io.Writer) bool {
+	f, ok := wr.(*os.File)
+	if !ok {
+		return false
+	}
+	fi, err := f.Stat()
+	return err == nil && fi.Mode()&os.ModeCharDevice != 0
+}
+
+// textSink formats a report. Rows are collected until End(), since the column widths depend on all rows, except for JSONLines reports, which are written row by row.
+type textSink struct {
+	wr     io.Writer        // Destination
+	format Format           // Output format, OutputFormat unless overridden
+	color  bool             // Highlight hot timers
+	rows   []ReportRow      // Collected rows
+	cols   []column         // Optional columns
+	rLen   *reportLen       // Column widths
+	wall   time.Duration    // Wall-clock window of the "% of wall" column, not shown when 0
+	lines  *jsonLinesWriter // Writes the rows of a JSONLines report as they arrive, nil for other formats
+	err    error            // First error of formatting, see fail()
+}
+
+// newTextSink returns a sink that writes to wr. The original writer, before any buffering, determines whether colors can be used.
+func newTextSink(wr, orig io.Writer) *textSink {
+	return &textSink{wr: wr, format: OutputFormat, color: ReportColor && isTerminal(orig)}
+}
+
+func (s *textSink) Begin() {
+	s.rows = nil
+	if s.format == JSONLines {
+		s.lines = &jsonLinesWriter{enc: json.NewEncoder(s.wr)}
+	}
+}
+
+func (s *textSink) Row(r ReportRow) {
+	if s.lines != nil {
+		s.lines.row(r)
+		return
+	}
+	s.rows = append(s.rows, r)
+}
+
+// End writes the collected rows, honoring ReportMaxRows and ReportEmptyMessage. JSON, YAML, HTML and JSONLines reports are always complete, so these don't apply to them.
+func (s *textSink) End() {
+	rows := s.rows
+	switch s.format {
+	case JSON:
+		reportJSON(s.wr, rows)
+		return
+	case YAML:
+		reportYAML(s.wr, rows)
+		return
+	case HTML:
+		reportHTML(s.wr, rows)
+		return
+	case JSONLines:
+		// Already written by Row().
+		return
+	}
+	if ReportHeader != "" && (s.format == Table || s.format == PlainText) {
+		fmt.Fprintf(s.wr, "=== %s %s ===\n", ReportHeader, DefaultClock.Now().Format(time.RFC3339))
+	}
+	if len(rows) == 0 {
+		if ReportEmptyMessage != "" {
+			fmt.Fprintln(s.wr, ReportEmptyMessage)
+		}
+		return
+	}
+	// Connectors are drawn as in the full report, so that a timer whose siblings were omitted isn't drawn as the last one.
+	indents := treeIndents(rows)
+	var omitted int
+	if ReportMaxRows > 0 && len(rows) > ReportMaxRows {
+		omitted = len(rows) - ReportMaxRows
+		rows, indents = rows[:ReportMaxRows], indents[:ReportMaxRows]
+	}
+
+	s.cols = extraColumns(rows)
+	if s.wall > 0 {
+		s.cols = append(s.cols, wallColumn(s.wall))
+	}
+	cells := make([][]string, len(rows))
+	for i, r := range rows {
+		for _, c := range s.cols {
+			cells[i] = append(cells[i], c.value(r))
+		}
+	}
+	if ShowGrandTotal {
+		rows = append(slices.Clip(rows), grandTotal(s.rows))
+		cells = append(cells, make([]string, len(s.cols)))
+		indents = append(slices.Clip(indents), "")
+	}
+
+	s.rLen = calculateLengths(rows, indents, s.cols, cells)
+	for i, r := range rows {
+		last := i == len(rows)-1 || rows[i+1].Depth == 0
+		var hl highlight
+		if s.color {
+			hl = r.highlight()
+		}
+		switch s.format {
+		case Table:
+			s.reportTable(r, indents[i], last, hl, cells[i])
+		case PlainText:
+			s.reportPlainText(r, indents[i], hl, cells[i])
+		case CSV:
+			s.reportCSV(r, cells[i])
+		case TSV:
+			s.reportTSV(r, cells[i])
+		}
+	}
+
+	if omitted > 0 {
+		fmt.Fprintf(s.wr, "… truncated, %d more timers\n", omitted)
+	}
+	if ReportLegend && s.format != CSV && s.format != TSV {
+		fmt.Fprintln(s.wr, legend(s.cols))
+	}
+	if ReportDigest && s.format != CSV && s.format != TSV {
+		fmt.Fprintf(s.wr, "Digest: %s\n", digest(s.rows))
+	}
+}
+
+// grandTotal returns the row that sums the roots, see ShowGrandTotal. Children aren't summed, as their time is part of their root's total.
+func grandTotal(rows []ReportRow) ReportRow {
+	t := ReportRow{Name: GrandTotalLabel, total: true}
+	for _, r := range rows {
+		if r.Depth == 0 {
+			t.Total += r.stats.TotalElapsed
+			t.Calls += r.Calls
+		}
+	}
+	t.Self = t.Total
+	t.Average = average(t.Total, t.Calls)
+	return t
+}
+
+// totalLabel returns the label of the total in Table reports, which depends on ReportExclusive.
+func totalLabel() string {
+	if ReportExclusive {
+		return ExclusiveLabel
+	}
+	return TotalLabel
+}
+
+// legend returns a one-line explanation of the report columns.
+func legend(cols []column) string {
+	parts := []string{
+		TotalLabel + ": summed duration of all calls",
+		CallsLabel + ": number of calls",
+	}
+	if ReportExclusive {
+		parts[0] = ExclusiveLabel + ": summed duration of all calls, minus the time of the children"
+	}
+	if ShowAverage {
+		parts = append(parts, AverageLabel+": total time divided by the number of calls")
+	}
+	for _, c := range cols {
+		parts = append(parts, c.label+": "+c.legend)
+	}
+	return "Legend: " + strings.Join(parts, "; ") + ". Durations are shown as e.g. 1.5ms (milliseconds) or 2m3s (minutes and seconds)."
+}
+
+// highlight wraps a value in ANSI color codes, the zero value doesn't.
+type highlight string
+
+const (
+	hot  highlight = "\x1b[31m" // Red
+	warm highlight = "\x1b[33m" // Yellow
+)
+
+func (h highlight) wrap(s string) string {
+	if h == "" {
+		return s
+	}
+	return string(h) + s + "\x1b[0m"
+}
+
+// highlight returns how the row should be highlighted, given its share of the root's total. Roots aren't highlighted.
+func (r ReportRow) highlight() highlight {
+	if r.Depth == 0 || r.rootTotal <= 0 {
+		return ""
+	}
+	pct := float64(r.Total) / float64(r.rootTotal) * 100
+	switch {
+	case pct >= ColorHotPercent:
+		return hot
+	case pct >= ColorWarmPercent:
+		return warm
+	}
+	return ""
+}
+
+// treeIndents returns the indentation of the names of the rows, which depends on their depth, IndentUnit and TreeConnectors.
+func treeIndents(rows []ReportRow) []string {
+	indents := make([]string, len(rows))
+	if !TreeConnectors {
+		for i, r := range rows {
+			indents[i] = strings.Repeat(IndentUnit, r.Depth)
+		}
+		return indents
+	}
+
+	w := max(utf8.RuneCountInString(IndentUnit), 1)
+	var (
+		branch = "├" + strings.Repeat("─", w-1)
+		last   = "└" + strings.Repeat("─", w-1)
+		pipe   = "│" + strings.Repeat(" ", w-1)
+		blank  = strings.Repeat(" ", w)
+	)
+	// hasNext reports whether the row at i is followed by a sibling, i.e., a row at the same depth before any shallower row.
+	hasNext := func(i int) bool {
+		for _, r := range rows[i+1:] {
+			if r.Depth <= rows[i].Depth {
+				return r.Depth == rows[i].Depth
+			}
+		}
+		return false
+	}
+	var open []bool // Per depth, whether the current ancestor at that depth is followed by a sibling
+	for i, r := range rows {
+		open = append(open[:r.Depth], hasNext(i))
+		if r.Depth == 0 {
+			continue
+		}
+		var b strings.Builder
+		for _, o := range open[1:r.Depth] {
+			if o {
+				b.WriteString(pipe)
+			} else {
+				b.WriteString(blank)
+			}
+		}
+		if open[r.Depth] {
+			b.WriteString(branch)
+		} else {
+			b.WriteString(last)
+		}
+		indents[i] = b.String()
+	}
+	return indents
+}
+
+func calculateLengths(rows []ReportRow, indents []string, cols []column, cells [][]string) *reportLen {
+	lengths := &reportLen{extraLens: make([]int, len(cols))}
+	for i, r := range rows {
+		lengths.leaderLen = max(lengths.leaderLen, utf8.RuneCountInString(indents[i])+len(r.Name))
+		lengths.totalLen = max(lengths.totalLen, len(DurationFormat(r.Total)))
+		lengths.callsLen = max(lengths.callsLen, len(fmt.Sprintf("%v", r.Calls)))
+		if ShowAverage && r.Calls > 0 {
+			lengths.avgLen = max(lengths.avgLen, len(DurationFormat(r.Average)))
+		}
+	}
+	for _, rowCells := range cells {
+		for i, cell := range rowCells {
+			lengths.extraLens[i] = max(lengths.extraLens[i], len(cell))
+		}
+	}
+	return lengths
+}
+
+// reportTable writes one row of a table. Each root starts a new table, the last row of a root ends it.
+func (s *textSink) reportTable(r ReportRow, indent string, last bool, hl highlight, cells []string) {
+	wr, rLen := s.wr, s.rLen
+	ruler := func(rLen *reportLen) {
+		if !TableRulers {
+			return
+		}
+		fmt.Fprint(wr, "+")
+		for i := 0; i < rLen.leaderLen+2; i++ {
+			fmt.Fprint(wr, "-")
+		}
+		fmt.Fprint(wr, "+")
+		for i := 0; i < rLen.totalLen+2; i++ {
+			fmt.Fprint(wr, "-")
+		}
+		fmt.Fprint(wr, "+")
+		for i := 0; i < rLen.callsLen+2; i++ {
+			fmt.Fprint(wr, "-")
+		}
+		if ShowAverage {
+			fmt.Fprint(wr, "+")
+			for i := 0; i < rLen.avgLen+2; i++ {
+				fmt.Fprint(wr, "-")
+			}
+		}
+		for _, l := range rLen.extraLens {
+			fmt.Fprint(wr, "+")
+			for i := 0; i < l+2; i++ {
+				fmt.Fprint(wr, "-")
+			}
+		}
+		fmt.Fprintln(wr, "+")
+	}
+	if r.Depth == 0 && !r.total {
+		rLen.leaderLen = max(rLen.leaderLen, len(TimerLabel))
+		rLen.totalLen = max(rLen.totalLen, len(totalLabel()))
+		rLen.callsLen = max(rLen.callsLen, len(CallsLabel))
+		if ShowAverage {
+			rLen.avgLen = max(rLen.avgLen, len(AverageLabel))
+		}
+		for i, c := range s.cols {
+			rLen.extraLens[i] = max(rLen.extraLens[i], len(c.label))
+		}
+
+		ruler(rLen)
+		fmt.Fprintf(wr, "| %-*s | %*s | %*s |",
+			rLen.leaderLen, TimerLabel,
+			rLen.totalLen, totalLabel(),
+			rLen.callsLen, CallsLabel)
+		if ShowAverage {
+			fmt.Fprintf(wr, " %*s |", rLen.avgLen, AverageLabel)
+		}
+		for i, c := range s.cols {
+			fmt.Fprintf(wr, " %*s |", rLen.extraLens[i], c.label)
+		}
+		fmt.Fprintln(wr)
+		ruler(rLen)
+	}
+	fmt.Fprint(wr, "| ")
+	fmt.Fprint(wr, indent, r.Name)
+	for printed := utf8.RuneCountInString(indent) + len(r.Name); printed <= rLen.leaderLen; printed++ {
+		fmt.Fprint(wr, " ")
+	}
+
+	fmt.Fprintf(wr, "| %s | %*v |",
+		hl.wrap(fmt.Sprintf("%*s", rLen.totalLen, DurationFormat(r.Total))),
+		rLen.callsLen, r.Calls)
+	if ShowAverage {
+		var avg string
+		if r.Calls > 0 {
+			avg = DurationFormat(r.Average)
+		}
+		fmt.Fprintf(wr, " %s |", hl.wrap(fmt.Sprintf("%*v", rLen.avgLen, avg)))
+	}
+	for i, cell := range cells {
+		fmt.Fprintf(wr, " %*s |", rLen.extraLens[i], cell)
+	}
+	fmt.Fprintln(wr)
+
+	if last {
+		ruler(rLen)
+	}
+}
+
+func (s *textSink) reportPlainText(r ReportRow, indent string, hl highlight, cells []string) {
+	wr, rLen := s.wr, s.rLen
+	fmt.Fprint(wr, indent, r.Name)
+	for printed := utf8.RuneCountInString(indent) + len(r.Name); printed <= rLen.leaderLen; printed++ {
+		fmt.Fprint(wr, " ")
+	}
+	word := "total"
+	if ReportExclusive {
+		word = "exclusive"
+	}
+	fmt.Fprintf(wr, "%s %s in %*v calls", word,
+		hl.wrap(fmt.Sprintf("%*s", rLen.totalLen, DurationFormat(r.Total))), rLen.callsLen, r.Calls)
+	if ShowAverage && r.Calls > 0 {
+		fmt.Fprintf(wr, ", avg %s",
+			hl.wrap(fmt.Sprintf("%*s", rLen.avgLen, DurationFormat(r.Average))))
+	}
+	for i, cell := range cells {
+		if cell != "" {
+			fmt.Fprintf(wr, ", "+s.cols[i].plain, cell)
+		}
+	}
+	fmt.Fprintln(wr)
+}
+
+func (s *textSink) reportCSV(r ReportRow, cells []string) {
+	cw := csv.NewWriter(s.wr)
+	cw.Comma = CSVDelimiter
+	header, record := s.records(r, cells)
+	for _, rec := range [][]string{header, record} {
+		if rec == nil {
+			continue
+		}
+		if err := cw.Write(rec); err != nil {
+			// E.g. an invalid CSVDelimiter
+			s.fail(err)
+			return
+		}
+	}
+	cw.Flush()
+	s.fail(cw.Error())
+}
+
+// fail retains err as the error of the report, unless an earlier error was retained.
+func (s *textSink) fail(err error) {
+	if s.err == nil {
+		s.err = err
+	}
+}
+
+// finish completes the report using flush, see reportWriter(), and returns the first error of formatting or writing.
+func (s *textSink) finish(flush func() error) error {
+	if err := flush(); err != nil {
+		return err
+	}
+	return s.err
+}
+
+// reportTSV writes a row as tab-separated values, with the columns of reportCSV. Tabs and line breaks in the values, e.g. in timer names, are replaced by spaces, as TSV has no quoting.
+func (s *textSink) reportTSV(r ReportRow, cells []string) {
+	header, record := s.records(r, cells)
+	for _, rec := range [][]string{header, record} {
+		if rec == nil {
+			continue
+		}
+		for i, v := range rec {
+			rec[i] = tsvReplacer.Replace(v)
+		}
+		fmt.Fprintln(s.wr, strings.Join(rec, "\t"))
+	}
+}
+
+var tsvReplacer = strings.NewReplacer("\t", " ", "\r\n", " ", "\n", " ", "\r", " ")
+
+// records returns the fields of a row in CSV and TSV reports, and for the first row of a root, the header fields that precede it.
+func (s *textSink) records(r ReportRow, cells []string) (header, record []string) {
+	if r.Depth == 0 && !r.total {
+		header = []string{"Timer", "Total", "Calls"}
+		if ReportExclusive {
+			header[1] = "Exclusive"
+		}
+		if ShowAverage {
+			header = append(header, "Average")
+		}
+		for _, c := range s.cols {
+			header = append(header, c.label)
+		}
+	}
+	record = []string{r.Name, DurationFormat(r.Total), strconv.Itoa(r.Calls)}
+	if ShowAverage {
+		var avg string
+		if r.Calls > 0 {
+			avg = DurationFormat(r.Average)
+		}
+		record = append(record, avg)
+	}
+	return header, append(record, cells...)
+}
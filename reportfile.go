@@ -0,0 +1,51 @@
+package calltimer
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"time"
+)
+
+/*
+ReportToFile appends a report of all timers to the file at path, like ReportAll in the current OutputFormat, preceded by a line with the time of the report. This suits periodic dumps of long-running processes:
+
+	for range time.Tick(time.Hour) {
+		calltimer.ReportToFile("/var/log/myapp/timers.log", 10<<20)
+	}
+
+When appending the report would grow the file beyond maxBytes, the file is rotated first: it's renamed to path with ".1" appended, replacing an earlier rotated file, and the report starts a new file. A maxBytes of 0 or less means no limit. Nothing is written when Active is false.
+*/
+func ReportToFile(path string, maxBytes int64) error {
+	if !Active {
+		return nil
+	}
+	var b bytes.Buffer
+	fmt.Fprintf(&b, "=== %s\n", DefaultClock.Now().Format(time.RFC3339))
+	if err := ReportAll(&b); err != nil {
+		return err
+	}
+
+	fi, err := os.Stat(path)
+	switch {
+	case errors.Is(err, fs.ErrNotExist):
+	case err != nil:
+		return err
+	case maxBytes > 0 && fi.Size() > 0 && fi.Size()+int64(b.Len()) > maxBytes:
+		if err := os.Rename(path, path+".1"); err != nil {
+			return err
+		}
+	}
+
+	f, err := os.OpenFile(path, os.O_WRONLY|os.O_APPEND|os.O_CREATE, 0o644)
+	if err != nil {
+		return err
+	}
+	if _, err := f.Write(b.Bytes()); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
@@ -0,0 +1,47 @@
+package calltimer
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestReportToFile(t *testing.T) {
+	resetGlobals()
+	defer func() { OutputFormat, DefaultClock = Table, realClock{} }()
+	DefaultClock = NewFakeClock(time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC))
+
+	MustNew("root", nil).LogDuration(time.Second)
+	OutputFormat = CSV
+	path := filepath.Join(t.TempDir(), "timers.log")
+	report := "=== 2024-01-02T03:04:05Z\nTimer;Total;Calls;Average\nroot;1s;1;1s\n"
+
+	for _, test := range []struct {
+		maxBytes    int64
+		wantFile    string
+		wantRotated string
+	}{
+		{maxBytes: 0, wantFile: report},
+		{maxBytes: 0, wantFile: report + report},
+		{maxBytes: int64(3 * len(report)), wantFile: report + report + report},
+		{maxBytes: int64(3 * len(report)), wantFile: report, wantRotated: report + report + report},
+	} {
+		if err := ReportToFile(path, test.maxBytes); err != nil {
+			t.Fatalf("ReportToFile(_, %v) = %v", test.maxBytes, err)
+		}
+		got, err := os.ReadFile(path)
+		if err != nil || string(got) != test.wantFile {
+			t.Errorf("after ReportToFile(_, %v): file = %q, %v, want %q", test.maxBytes, got, err, test.wantFile)
+		}
+		rotated, _ := os.ReadFile(path + ".1")
+		if string(rotated) != test.wantRotated {
+			t.Errorf("after ReportToFile(_, %v): rotated file = %q, want %q", test.maxBytes, rotated, test.wantRotated)
+		}
+	}
+
+	if err := ReportToFile(filepath.Join(path, "not-a-dir"), 0); err == nil || !strings.Contains(err.Error(), "not-a-dir") {
+		t.Errorf("ReportToFile() in a file = %v, want error", err)
+	}
+}
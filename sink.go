@@ -0,0 +1,60 @@
+package calltimer
+
+import "time"
+
+/*
+ReportSink receives the rows of a report, so that reports can be sent to arbitrary destinations, such as a chat channel or a database. Begin is called once before the rows, Row once for each timer, and End once after the rows. The built-in formats are implemented as sinks, too.
+*/
+type ReportSink interface {
+	Begin()
+	Row(ReportRow)
+	End()
+}
+
+/*
+ReportRow describes one timer in a report. Rows are delivered depth-first: a root, then its children, and so on.
+*/
+type ReportRow struct {
+	Timer   *Timer        // The reported timer
+	Root    *Timer        // The root timer of this part of the report
+	Depth   int           // Nesting level under Root, 0 for Root itself
+	Name    string        // Timer name
+	Total   time.Duration // Total duration
+	Calls   int           // Number of invocations
+	Average time.Duration // Average duration, 0 when there were no invocations
+	Self    time.Duration // Total minus the totals of the children, at least 0
+	Overlap bool          // The totals of the children exceed Total, e.g. because they ran in parallel
+	Hidden  int           // Number of rows below this one that are collapsed due to ReportMaxDepth
+
+	stats       *Timer        // Copy of the timer's statistics when the report was made
+	parentTotal time.Duration // Total of the parent, 0 for the reported roots
+	rootTotal   time.Duration // Total of Root
+	total       bool          // The sum of the roots, see ShowGrandTotal
+}
+
+/*
+ReportAllTo is like ReportAll, but sends the rows of the report to a sink, instead of formatting them.
+*/
+func ReportAllTo(sink ReportSink) {
+	defaultRegistry.ReportAllTo(sink)
+}
+
+/*
+ReportAllTo is like the package-level ReportAllTo(), but sends the rows of the timers of this registry.
+*/
+func (r *Registry) ReportAllTo(sink ReportSink) {
+	if !Active {
+		return
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	sendReport(sink, r.roots, (*Timer).hasOwnActivity)
+}
+
+// sendReport drives a sink through a report on the passed-in roots, see reportRows(). The rows are sent while the timers are walked.
+func sendReport(sink ReportSink, ts []*Timer, include func(*Timer) bool) {
+	sink.Begin()
+	walkRows(ts, include, sink.Row)
+	sink.End()
+}
@@ -0,0 +1,42 @@
+package calltimer
+
+import (
+	"testing"
+	"time"
+)
+
+// recordingSink records the calls that it receives.
+type recordingSink struct {
+	events []string
+	rows   []ReportRow
+}
+
+func (s *recordingSink) Begin() { s.events = append(s.events, "begin") }
+func (s *recordingSink) End()   { s.events = append(s.events, "end") }
+func (s *recordingSink) Row(r ReportRow) {
+	s.events = append(s.events, "row")
+	s.rows = append(s.rows, r)
+}
+
+func TestReportAllTo(t *testing.T) {
+	resetGlobals()
+
+	r := MustNew("root", nil)
+	c := MustNew("child", r)
+	MustNew("idle", r)
+	r.LogDuration(3 * time.Second)
+	c.LogDuration(time.Second)
+	c.LogDuration(time.Second)
+
+	s := &recordingSink{}
+	ReportAllTo(s)
+	if len(s.events) != 4 || s.events[0] != "begin" || s.events[3] != "end" {
+		t.Fatalf("sink events = %v, want [begin row row end]", s.events)
+	}
+	want := ReportRow{Timer: c, Root: r, Depth: 1, Name: "child", Total: 2 * time.Second, Calls: 2, Average: time.Second, Self: 2 * time.Second}
+	got := s.rows[1]
+	got.stats, got.parentTotal, got.rootTotal = nil, 0, 0
+	if got != want {
+		t.Errorf("second row = %+v, want %+v", got, want)
+	}
+}
@@ -0,0 +1,96 @@
+package calltimer
+
+import (
+	"maps"
+	"math"
+	"slices"
+	"time"
+)
+
+// sketchAccuracy is the relative accuracy of percentiles that are estimated by a sketch.
+const sketchAccuracy = 0.01
+
+var (
+	sketchGamma    = (1 + sketchAccuracy) / (1 - sketchAccuracy)
+	sketchLogGamma = math.Log(sketchGamma)
+)
+
+/*
+sketch estimates percentiles of durations in bounded memory. Durations are counted in buckets whose bounds grow exponentially, so that an estimate is within sketchAccuracy of a true value. Covering durations from 1ns up to a day takes fewer than 2000 buckets.
+*/
+type sketch struct {
+	counts map[int]int // Number of durations per bucket index
+	zeros  int         // Number of durations of zero or less
+	total  int         // Number of durations
+}
+
+func newSketch() *sketch {
+	return &sketch{counts: map[int]int{}}
+}
+
+// add counts duration d, n times.
+func (s *sketch) add(d time.Duration, n int) {
+	s.total += n
+	if d <= 0 {
+		s.zeros += n
+		return
+	}
+	s.counts[int(math.Ceil(math.Log(float64(d))/sketchLogGamma))] += n
+}
+
+// percentile returns the estimated p-th percentile, or 0 when nothing was counted.
+func (s *sketch) percentile(p float64) time.Duration {
+	if s.total == 0 {
+		return 0
+	}
+	rank := int(math.Ceil(min(max(p, 0), 100) / 100 * float64(s.total)))
+	seen := s.zeros
+	if rank <= seen {
+		return 0
+	}
+	keys := make([]int, 0, len(s.counts))
+	for k := range s.counts {
+		keys = append(keys, k)
+	}
+	slices.Sort(keys)
+	for _, k := range keys {
+		seen += s.counts[k]
+		if seen >= rank {
+			// The midpoint of bucket k, which holds durations in (gamma^(k-1), gamma^k].
+			return time.Duration(2 * math.Pow(sketchGamma, float64(k)) / (sketchGamma + 1))
+		}
+	}
+	return 0
+}
+
+func (s *sketch) clone() *sketch {
+	if s == nil {
+		return nil
+	}
+	c := *s
+	c.counts = maps.Clone(s.counts)
+	return &c
+}
+
+/*
+WithApproxPercentiles enables estimating percentiles of the durations of a timer, see ApproxPercentile(). The estimate uses a fixed amount of memory, regardless of the number of invocations.
+*/
+func WithApproxPercentiles() Option {
+	return func(t *Timer) error {
+		t.sketch = newSketch()
+		return nil
+	}
+}
+
+/*
+ApproxPercentile returns an estimate of the p-th percentile (0-100) of the durations of the timer; for example, ApproxPercentile(99) estimates the p99. The estimate is within 1% of a true duration. The timer must be created with the option WithApproxPercentiles(), otherwise the returned value is 0.
+*/
+func (t *Timer) ApproxPercentile(p float64) time.Duration {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if t.sketch == nil {
+		return 0
+	}
+	return t.sketch.percentile(p)
+}
@@ -0,0 +1,33 @@
+package calltimer
+
+import (
+	"testing"
+	"time"
+)
+
+func TestApproxPercentile(t *testing.T) {
+	resetGlobals()
+
+	tm := MustNewWith("sketched", nil, WithApproxPercentiles())
+	for i := 1000; i >= 1; i-- {
+		tm.LogDuration(time.Duration(i) * time.Millisecond)
+	}
+	for _, test := range []struct {
+		p    float64
+		want time.Duration
+	}{
+		{50, 500 * time.Millisecond},
+		{90, 900 * time.Millisecond},
+		{99, 990 * time.Millisecond},
+		{100, 1000 * time.Millisecond},
+	} {
+		got := tm.ApproxPercentile(test.p)
+		if diff := got - test.want; diff < -test.want/100 || diff > test.want/100 {
+			t.Errorf("ApproxPercentile(%v) = %v, want %v within 1%%", test.p, got, test.want)
+		}
+	}
+
+	if got := MustNew("plain", nil).ApproxPercentile(50); got != 0 {
+		t.Errorf("ApproxPercentile() without sketch = %v, want 0", got)
+	}
+}
@@ -0,0 +1,42 @@
+package calltimer
+
+import (
+	"context"
+	"log/slog"
+)
+
+/*
+LogSlog emits a log record per timer with activity, for the timer and its descendants, to logger. This keeps the timing data in structured logs, instead of a separate report. Each record has the message "calltimer" and the attributes name, total, calls, avg (unless ShowAverage is false) and parent, where parent is empty for a root timer. For example, with a slog.JSONHandler:
+
+	{"time":"...","level":"INFO","msg":"calltimer","name":"query","total":1500000000,"calls":3,"avg":500000000,"parent":"main"}
+
+Nothing is logged when Active is false.
+*/
+func (t *Timer) LogSlog(logger *slog.Logger) {
+	if !Active {
+		return
+	}
+	reg := t.registry()
+	reg.mu.Lock()
+	defer reg.mu.Unlock()
+
+	for _, r := range reportRows([]*Timer{t}, (*Timer).hasOwnActivity) {
+		if !r.stats.hasOwnActivity() {
+			continue // Reported for the context of its children
+		}
+		var parent string
+		if r.stats.Parent != nil {
+			parent = r.stats.Parent.Name
+		}
+		attrs := []slog.Attr{
+			slog.String("name", r.Name),
+			slog.Duration("total", r.stats.TotalElapsed),
+			slog.Int("calls", r.stats.CalledTimes),
+		}
+		if ShowAverage {
+			attrs = append(attrs, slog.Duration("avg", average(r.stats.TotalElapsed, r.stats.CalledTimes)))
+		}
+		attrs = append(attrs, slog.String("parent", parent))
+		logger.LogAttrs(context.Background(), slog.LevelInfo, "calltimer", attrs...)
+	}
+}
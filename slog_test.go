@@ -0,0 +1,47 @@
+package calltimer
+
+import (
+	"bytes"
+	"log/slog"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestLogSlog(t *testing.T) {
+	resetGlobals()
+
+	r := MustNew("root", nil)
+	a := MustNew("a", r)
+	MustNew("b", a).LogDuration(2 * time.Second)
+	MustNew("idle", r)
+	r.LogDuration(3 * time.Second)
+
+	var buf bytes.Buffer
+	logger := slog.New(slog.NewTextHandler(&buf, &slog.HandlerOptions{
+		ReplaceAttr: func(groups []string, a slog.Attr) slog.Attr {
+			if a.Key == slog.TimeKey {
+				return slog.Attr{}
+			}
+			return a
+		},
+	}))
+	r.LogSlog(logger)
+	want := []string{
+		"level=INFO msg=calltimer name=root total=3s calls=1 avg=3s parent=\"\"",
+		"level=INFO msg=calltimer name=b total=2s calls=1 avg=2s parent=a",
+	}
+	if got := strings.Split(strings.TrimSpace(buf.String()), "\n"); strings.Join(got, "\n") != strings.Join(want, "\n") {
+		t.Errorf("LogSlog() logged %q, want %q", got, want)
+	}
+
+	defer func() { ShowAverage = true }()
+	ShowAverage = false
+	buf.Reset()
+	c := MustNew("c", nil)
+	c.LogDuration(time.Second)
+	c.LogSlog(logger)
+	if want := "level=INFO msg=calltimer name=c total=1s calls=1 parent=\"\"\n"; buf.String() != want {
+		t.Errorf("LogSlog() without average logged %q, want %q", buf.String(), want)
+	}
+}
@@ -0,0 +1,74 @@
+package calltimer
+
+import "unsafe"
+
+/*
+RegistryStats describes the registered timers, see Stats().
+*/
+type RegistryStats struct {
+	Timers      int // Number of timers
+	Roots       int // Number of root timers
+	Leaves      int // Number of timers without children
+	MaxDepth    int // Number of levels of the deepest tree, 1 when there are only roots
+	MemoryBytes int // Rough estimate of the memory that the timers occupy
+}
+
+// mapEntryBytes is a rough estimate of the overhead of one entry in the map of timers.
+const mapEntryBytes = 48
+
+/*
+Stats returns statistics of the registered timers, which help to understand the overhead of instrumentation, especially when timers are created dynamically.
+*/
+func Stats() RegistryStats {
+	return defaultRegistry.Stats()
+}
+
+/*
+Stats is like the package-level Stats(), but describes the timers of this registry.
+*/
+func (r *Registry) Stats() RegistryStats {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	st := RegistryStats{Roots: len(r.roots)}
+	var walk func(t *Timer, depth int)
+	walk = func(t *Timer, depth int) {
+		st.Timers++
+		st.MaxDepth = max(st.MaxDepth, depth)
+		if len(t.Children) == 0 {
+			st.Leaves++
+		}
+		st.MemoryBytes += t.memoryBytes()
+		for _, c := range t.Children {
+			walk(c, depth+1)
+		}
+	}
+	for _, root := range r.roots {
+		walk(root, 1)
+	}
+	return st
+}
+
+// memoryBytes estimates the memory that the timer occupies, including its entry in the map of timers.
+func (t *Timer) memoryBytes() int {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	n := int(unsafe.Sizeof(*t)) + len(t.Name) + mapEntryBytes
+	n += cap(t.Children) * int(unsafe.Sizeof(t))
+	for _, tag := range t.Tags {
+		n += int(unsafe.Sizeof(tag)) + len(tag)
+	}
+	for k, v := range t.slowestFields {
+		n += len(k) + len(v) + mapEntryBytes
+	}
+	n += len(t.buckets)*int(unsafe.Sizeof(t.TotalElapsed)) + len(t.bucketCounts)*int(unsafe.Sizeof(t.CalledTimes))
+	n += cap(t.samples) * int(unsafe.Sizeof(t.TotalElapsed))
+	for tag, st := range t.tagged {
+		n += len(tag) + int(unsafe.Sizeof(st)) + mapEntryBytes
+	}
+	if t.sketch != nil {
+		n += int(unsafe.Sizeof(*t.sketch)) + len(t.sketch.counts)*mapEntryBytes
+	}
+	return n
+}
@@ -0,0 +1,20 @@
+package calltimer
+
+import "testing"
+
+func TestStats(t *testing.T) {
+	resetGlobals()
+
+	MustNewPath("a/b/c")
+	MustNewPath("a/d")
+	MustNew("e", nil)
+
+	got := Stats()
+	want := RegistryStats{Timers: 5, Roots: 2, Leaves: 3, MaxDepth: 3, MemoryBytes: got.MemoryBytes}
+	if got != want {
+		t.Errorf("Stats() = %+v, want %+v", got, want)
+	}
+	if got.MemoryBytes <= 0 {
+		t.Errorf("Stats().MemoryBytes = %v, want > 0", got.MemoryBytes)
+	}
+}
@@ -0,0 +1,33 @@
+package calltimer
+
+import (
+	"math"
+	"time"
+)
+
+/*
+StdDev returns the sample standard deviation of the durations of the timer, or 0 when fewer than two durations were logged. It's computed using Welford's online algorithm, so unlike Percentile() it doesn't need to retain the durations. For weighted and sampled calls (see LogDurationWeighted() and WithSampling()), the duration per item counts once per call that it represents.
+*/
+func (t *Timer) StdDev() time.Duration {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	return t.stdDev()
+}
+
+// stdDev implements StdDev(). The timer must be locked, or be a copy.
+func (t *Timer) stdDev() time.Duration {
+	if t.varCount < 2 {
+		return 0
+	}
+	return time.Duration(math.Sqrt(t.m2 / float64(t.varCount-1)))
+}
+
+// addVariance adds a duration that represents n calls to the running variance. The timer must be locked.
+func (t *Timer) addVariance(d time.Duration, n int) {
+	x := float64(d)
+	t.varCount += n
+	delta := x - t.mean
+	t.mean += delta * float64(n) / float64(t.varCount)
+	t.m2 += float64(n) * delta * (x - t.mean)
+}
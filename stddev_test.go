@@ -0,0 +1,43 @@
+package calltimer
+
+import (
+	"bytes"
+	"testing"
+	"time"
+)
+
+func TestStdDev(t *testing.T) {
+	resetGlobals()
+	defer func() { OutputFormat, ShowStdDev = Table, false }()
+
+	r := MustNew("root", nil)
+	tm := MustNew("spiky", r)
+	r.LogDuration(time.Minute)
+	for _, d := range []time.Duration{2, 4, 4, 4, 5, 5, 7, 9} {
+		tm.LogDuration(d * time.Second)
+	}
+	// Mean is 5s, the sum of squared deviations is 32s², the sample variance 32/7.
+	want := time.Duration(2138089935) // sqrt(32/7) seconds
+	if got := tm.StdDev(); got < want-time.Microsecond || got > want+time.Microsecond {
+		t.Errorf("StdDev() = %v, want %v", got, want)
+	}
+
+	w := MustNew("weighted", r)
+	w.LogDurationWeighted(4*time.Second, 2) // Two items of 2s
+	w.LogDuration(5 * time.Second)
+	if got, want := w.StdDev(), time.Duration(1732050807); got < want-time.Microsecond || got > want+time.Microsecond {
+		t.Errorf("StdDev() with weights = %v, want %v", got, want)
+	}
+
+	OutputFormat = CSV
+	ShowStdDev = true
+	var b bytes.Buffer
+	ReportAll(&b)
+	wantReport := "Timer;Total;Calls;Average;Std. deviation\n" +
+		"root;1m0s;1;1m0s;\n" +
+		"spiky;40s;8;5s;2.138089935s\n" +
+		"weighted;9s;3;3s;1.732050807s\n"
+	if b.String() != wantReport {
+		t.Errorf("ReportAll() = %q, want %q", b.String(), wantReport)
+	}
+}
@@ -0,0 +1,57 @@
+package calltimer
+
+import (
+	"bytes"
+	"testing"
+	"time"
+)
+
+func TestLogSinceTagged(t *testing.T) {
+	resetGlobals()
+	defer func() { OutputFormat, ReportTagBreakdown = Table, false }()
+
+	virtual := time.Unix(0, 0)
+	tm := MustNewWith("handle", nil, WithClock(ClockFunc(func() time.Time { return virtual })))
+	for _, c := range []struct {
+		tag string
+		d   time.Duration
+	}{{"200", time.Second}, {"404", 3 * time.Second}, {"200", time.Second}} {
+		start := tm.Now()
+		virtual = virtual.Add(c.d)
+		tm.LogSinceTagged(start, c.tag)
+	}
+
+	if tm.TotalElapsed != 5*time.Second || tm.CalledTimes != 3 {
+		t.Errorf("after LogSinceTagged(): %v/%v, want 5s/3", tm.TotalElapsed, tm.CalledTimes)
+	}
+	if got := tm.TagBreakdown()["200"]; got != (TaggedStats{2 * time.Second, 2}) {
+		t.Errorf("TagBreakdown()[200] = %+v, want 2s/2", got)
+	}
+
+	OutputFormat = CSV
+	var b bytes.Buffer
+	ReportAll(&b)
+	if want := "Timer;Total;Calls;Average\nhandle;5s;3;1.666666666s\n"; b.String() != want {
+		t.Errorf("ReportAll() = %q, want %q", b.String(), want)
+	}
+	ReportTagBreakdown = true
+	b.Reset()
+	ReportAll(&b)
+	want := "Timer;Total;Calls;Average\nhandle;5s;3;1.666666666s\n[200];2s;2;1s\n[404];3s;1;3s\n"
+	if b.String() != want {
+		t.Errorf("ReportAll() with ReportTagBreakdown = %q, want %q", b.String(), want)
+	}
+}
+
+func TestLogSinceTaggedNegative(t *testing.T) {
+	resetGlobals()
+
+	tm := MustNew("skewed", nil)
+	tm.LogSinceTagged(time.Now().Add(time.Hour), "future")
+	if got := tm.TagBreakdown()["future"]; got != (TaggedStats{0, 1}) {
+		t.Errorf("TagBreakdown()[future] = %+v, want 0s/1", got)
+	}
+	if tm.TotalElapsed != 0 || tm.Clamped() != 1 {
+		t.Errorf("TotalElapsed, Clamped() = %v, %v, want 0s, 1", tm.TotalElapsed, tm.Clamped())
+	}
+}
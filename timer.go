@@ -4,21 +4,15 @@ Package calltimer implements very simple instrumentation to track and report the
 package calltimer
 
 import (
-	"errors"
 	"fmt"
-	"io"
+	"maps"
+	"regexp"
+	"slices"
+	"strings"
 	"sync"
 	"time"
 )
 
-// String lengths over all roots
-type reportLen struct {
-	leaderLen int // String length of indentation + name
-	totalLen  int // String length of total duration
-	callsLen  int // String length of # of calls
-	avgLen    int // String length of average duration
-}
-
 /*
 Timer holds timing information and is constructed using New() or MustNew().
 */
@@ -26,9 +20,38 @@ type Timer struct {
 	Name         string        // Timer name
 	TotalElapsed time.Duration // Total duration
 	CalledTimes  int           // Number of invocations
+	MaxElapsed   time.Duration // Duration of the slowest invocation
+	LastElapsed  time.Duration // Duration of the most recent invocation
+	Budget       time.Duration // Acceptable average duration, see WithBudget()
+	Tags         []string      // Free-form labels, see WithTags()
 	Parent       *Timer        // Parent, nil when this is a root timer
 	Children     []*Timer      // Dependent children
 	mu           sync.Mutex    // Per-timer lock
+	once         sync.Once     // See Once()
+
+	slowestFields map[string]string      // Context of the slowest invocation, see LogSinceCtx()
+	sampling      int                    // Record every n-th invocation, see WithSampling()
+	unsampled     int                    // Invocations since the last recorded one
+	buckets       []time.Duration        // Histogram bucket upper bounds, see WithHistogram()
+	bucketCounts  []int                  // Invocations per bucket, plus one for overflow
+	reportedCalls int                    // CalledTimes at the last ReportChanged()
+	sketch        *sketch                // Percentile estimator, see WithApproxPercentiles()
+	samples       []time.Duration        // Recorded durations, nil unless TrackDistribution() was called
+	tagged        map[string]TaggedStats // Statistics per tag, see LogSinceTagged()
+	windowStart   time.Time              // Start of the wall-clock window, see StartWindow()
+	windowEnd     time.Time              // End of the window, zero while it's open
+	disabled      bool                   // No recording, see SetEnabledPattern()
+	paused        bool                   // No recording, see SetActive()
+	varCount      int                    // Number of durations in the running variance, see StdDev()
+	mean          float64                // Running mean of the durations in nanoseconds
+	m2            float64                // Running sum of squared deviations from the mean
+	clock         Clock                  // Time source, see SetClock()
+	reg           *Registry              // Registry that the timer belongs to, nil when unregistered
+	entered       int                    // Number of Enter() calls without Exit()
+	inFlight      int                    // Number of calls in progress, see InFlight()
+	peakInFlight  int                    // Highest inFlight, see PeakInFlight()
+	clamped       int                    // Number of negative durations, see Clamped()
+	busySince     time.Time              // Time of the Enter() that made entered positive
 }
 
 /*
@@ -39,54 +62,214 @@ type Format int
 const (
 	Table     Format = iota // Present data as a table
 	PlainText               // Present data in somewhat readable text format
-	CSV                     // Present data as semicolon-separated values
-
-	leaderLabel = "Timer name"
-	totalLabel  = "Total time"
-	callsLabel  = "Nr. of calls"
-	avgLabel    = "Average time/call"
+	CSV                     // Present data as comma-separated values, using CSVDelimiter
+	JSON                    // Present data as a JSON array of nested timer objects
+	YAML                    // Present data as a YAML sequence of nested timer mappings, like JSON
+	TSV                     // Present data as tab-separated values, with the columns of CSV
+	HTML                    // Present data as nested HTML lists, with collapsible timers
+	JSONLines               // Present data as one JSON object per timer per line, with its depth and parent
 )
 
 var (
-	timers       = map[string]*Timer{}         // Map of timers to avoid duplicate names
-	roots        = []*Timer{}                  // List of roots to ReportAll()
-	mu           sync.Mutex                    // Global lock for manipulation of global vars
-	OutputFormat Format                = Table // Current output format, defaults to Table
+	OutputFormat Format = Table // Current output format, defaults to Table
+)
 
+/*
+The labels of the standard columns in the header of Table reports, and in the legend (see ReportLegend). They can be changed for e.g. localized or more compact reports. ExclusiveLabel replaces TotalLabel when ReportExclusive is true. GrandTotalLabel names the row of ShowGrandTotal, in all formats that show it. CSV reports keep their headers Timer, Total (or Exclusive), Calls and Average, so that programs that read them aren't affected.
+*/
+var (
+	TimerLabel      = "Timer name"
+	TotalLabel      = "Total time"
+	ExclusiveLabel  = "Exclusive time"
+	CallsLabel      = "Nr. of calls"
+	AverageLabel    = "Average time/call"
+	GrandTotalLabel = "TOTAL"
 )
 
 /*
-Active defaults to true. When set to false, no timing is recorded and no reports are generated.
+IndentUnit indents the names of timers in Table and PlainText reports, once per level below the root. It defaults to two spaces.
+*/
+var IndentUnit = "  "
+
+/*
+TreeConnectors defaults to false. When set to true, Table and PlainText reports connect the names of timers to their parents using box-drawing lines, like tree(1). This makes deep trees easier to follow. The connectors are as wide as IndentUnit:
+
+	root
+	├─a
+	│ └─b
+	└─c
+*/
+var TreeConnectors = false
+
+/*
+ReportExclusive defaults to false. When set to true, reports show the exclusive time of each timer instead of its total time, in all formats: the total minus the totals of its children, clamped at zero, which is the time that the timer spent in itself. The average is then the exclusive time per call. The header of Table, CSV and TSV reports shows the mode, using ExclusiveLabel respectively "Exclusive" instead of the label of the total, and PlainText lines say "exclusive" instead of "total". JSON, YAML and HTML reports keep their keys and classes. Percentages (see ShowPercentages) remain relative to the total time of the parent and the root.
+*/
+var ReportExclusive = false
+
+/*
+ShowAverage defaults to true. When set to false, reports omit the average duration per call, in all output formats.
+*/
+var ShowAverage = true
+
+/*
+CSVDelimiter separates the fields of CSV reports, and defaults to a semicolon. Fields are quoted as needed using encoding/csv, so that the reports can be read by spreadsheets and csv.Reader, even when timer names contain the delimiter, quotes or line breaks. The delimiter can't be a double quote, a line break or the Unicode replacement character; CSV reports then fail with an error.
+*/
+var CSVDelimiter = ';'
+
+/*
+PathSeparator separates the timer names in paths, see Path() and NewPath().
+*/
+var PathSeparator = "/"
+
+/*
+Active defaults to true. When set to false, no timing is recorded and no reports are generated. Timers are still created and registered, so that Active can be toggled at runtime.
 */
 var Active = true
 
 /*
-New creates a Timer. The passed-in name must be unique. When parent is nil, the timer is considered a root timer, meaning that ReportAll() picks it up.
+ReportMaxRows limits the number of timers that a report shows. The default, 0, means unlimited. When a report has more timers, the report is cut off and ends with a notice stating how many timers were omitted.
 */
-func New(name string, parent *Timer) (*Timer, error) {
-	if !Active {
-		return nil, nil
-	}
-	mu.Lock()
-	defer mu.Unlock()
+var ReportMaxRows = 0
 
-	// Name must exist and can't be redefined
-	_, ok := timers[name]
-	if name == "" {
-		return nil, errors.New("can't create a timer without a name")
-	}
-	if ok {
-		return nil, fmt.Errorf("timer %q is already defined", name)
-	}
+/*
+ReportMaxDepth limits the number of levels that a report shows, like du -d. The default, 0, means unlimited; 1 shows only the roots, 2 the roots and their children, and so on. Deeper timers are collapsed into their ancestor at the deepest shown level, which then shows the number of hidden timers.
+*/
+var ReportMaxDepth = 0
 
-	t := &Timer{Name: name, Children: []*Timer{}, Parent: parent}
-	timers[name] = t
-	if parent == nil {
-		roots = append(roots, t)
-	} else {
-		parent.Children = append(parent.Children, t)
+/*
+ReportColor defaults to false. When set to true and a Table or PlainText report is written to a terminal, the total and average of timers are highlighted in red when they take at least ColorHotPercent of their root timer's total, or in yellow when they take at least ColorWarmPercent.
+*/
+var (
+	ReportColor      = false
+	ColorHotPercent  = 50.0
+	ColorWarmPercent = 20.0
+)
+
+/*
+ShowUptimeShare defaults to false. When set to true, reports show the share of Uptime() that each root timer's total represents.
+*/
+var ShowUptimeShare = false
+
+/*
+TableRulers defaults to true. When set to false, Table reports omit the horizontal ruler lines, but keep the header and the column alignment.
+*/
+var TableRulers = true
+
+/*
+ReportLegend defaults to false. When set to true, Table and PlainText reports end with a line that explains the columns and the notation of durations, which makes reports self-explanatory for readers who are unfamiliar with Go.
+*/
+var ReportLegend = false
+
+/*
+ShowSiblingRatio defaults to false. When set to true, reports show each timer's total relative to the sibling (the timer with the same parent) that has the highest total. The hottest sibling is shown as 1.00x, and e.g. a sibling with a quarter of its time as 0.25x.
+*/
+var ShowSiblingRatio = false
+
+/*
+SortOrder defines the order of sibling timers in reports, see SortBy.
+*/
+type SortOrder int
+
+const (
+	SortNone      SortOrder = iota // Order of creation
+	SortTotalDesc                  // Highest total first
+	SortCallsDesc                  // Most calls first
+	SortNameAsc                    // Alphabetically by name
+)
+
+/*
+SortBy defaults to SortNone. It sets the order in which reports show the roots, and the children of each timer. Sorting doesn't change the timer tree. Siblings that are equal by the sort order keep their order of creation.
+*/
+var SortBy = SortNone
+
+/*
+ShowLast defaults to false. When set to true, reports show the duration of the most recent invocation of each timer, see LastElapsed. This helps to spot a recent regression that doesn't show in the average yet.
+*/
+var ShowLast = false
+
+/*
+ShowPeakInFlight defaults to false. When set to true, reports show the highest number of calls of each timer that were in progress at the same time, see PeakInFlight().
+*/
+var ShowPeakInFlight = false
+
+/*
+ShowStdDev defaults to false. When set to true, reports show the standard deviation of the durations of each timer, see StdDev(). A standard deviation that's large compared to the average indicates spiky timings.
+*/
+var ShowStdDev = false
+
+/*
+ShowPercentages defaults to false. When set to true, reports show each timer's total as a percentage of its parent's total, and as a percentage of its root's total. The percentage of the parent is blank for the reported roots.
+*/
+var ShowPercentages = false
+
+/*
+ShowGrandTotal defaults to false. When set to true, Table, PlainText, CSV and TSV reports end with a row named GrandTotalLabel that holds the summed totals and calls of the reported roots. Only roots are summed, as the time of children is already part of the total of their root.
+*/
+var ShowGrandTotal = false
+
+/*
+ReportHeader defaults to "". When set, e.g. to "calltimer report", Table and PlainText reports start with a line that holds the header and the time of the report, such as:
+
+	=== calltimer report 2024-03-01T12:00:00Z ===
+
+This tells periodic reports in a log apart. Other formats have their own structure and don't get the line.
+*/
+var ReportHeader = ""
+
+/*
+ReportEmptyMessage defaults to "". When set, reports without any timer activity consist of this message, e.g. "no timing data collected", so that an empty report can't be mistaken for a report that didn't run.
+*/
+var ReportEmptyMessage = ""
+
+/*
+DurationFormat renders the durations in Table, PlainText, CSV, TSV and HTML reports, in WriteDOT(), WriteFlat() and in the "top" command of Interactive(), and defaults to time.Duration's String(). It can be replaced to show e.g. milliseconds with two decimals throughout:
+
+	calltimer.DurationFormat = func(d time.Duration) string {
+		return fmt.Sprintf("%.2fms", float64(d)/float64(time.Millisecond))
 	}
-	return t, nil
+
+JSON, YAML and JSONLines reports aren't affected, they always hold nanoseconds.
+*/
+var DurationFormat = func(d time.Duration) string { return d.String() }
+
+/*
+ReportInclusiveExclusive defaults to false. When set to true, reports show the exclusive time of each timer next to its total (inclusive) time. The exclusive time is the total minus the totals of the timer's children, i.e., the time that the timer spent in itself. It's clamped at zero, as children that run in parallel may add up to more than their parent; such clamped values are flagged with an asterisk.
+*/
+var ReportInclusiveExclusive = false
+
+/*
+ShowRank defaults to false. When set to true, reports show the rank of each timer when ordering all reported timers by total time, where 1 is the timer with the highest total. This shows which timers are hot, regardless of their position in the tree.
+*/
+var ShowRank = false
+
+/*
+ReportDigest defaults to false. When set to true, Table and PlainText reports end with a digest of the reported timers, see Digest(). A report of a single timer shows the same digest as the timer's Digest().
+*/
+var ReportDigest = false
+
+/*
+ReportBuffered defaults to false. When set to true, Report and ReportAll collect their output in a bufio.Writer which is flushed once the report is complete. This saves many small writes when reporting to a file or to a slow sink, such as a network connection.
+*/
+var ReportBuffered = false
+
+/*
+New creates a Timer in the default registry. The passed-in name must be unique. When parent is nil, the timer is considered a root timer, meaning that ReportAll() picks it up.
+
+Timers don't need to be created at package initialization: they may be created lazily from concurrent goroutines, also under a shared parent, while other goroutines log and report.
+*/
+func New(name string, parent *Timer) (*Timer, error) {
+	return defaultRegistry.New(name, parent)
+}
+
+/*
+NewWith is like New, but additionally accepts options that enable optional features of the timer. For example:
+
+	t, err := calltimer.NewWith("query", nil,
+		calltimer.WithBudget(100*time.Millisecond),
+		calltimer.WithTags("db"))
+*/
+func NewWith(name string, parent *Timer, opts ...Option) (*Timer, error) {
+	return defaultRegistry.NewWith(name, parent, opts...)
 }
 
 /*
@@ -98,17 +281,280 @@ MustNew wraps New and panics upon error. The typical usage is:
 	  )
 */
 func MustNew(name string, parent *Timer) *Timer {
-	if !Active {
-		return nil
+	return defaultRegistry.MustNew(name, parent)
+}
+
+/*
+MustNewWith wraps NewWith and panics upon error.
+*/
+func MustNewWith(name string, parent *Timer, opts ...Option) *Timer {
+	return defaultRegistry.MustNewWith(name, parent, opts...)
+}
+
+/*
+NewUnder is like New, but the parent is specified by name. The parent must already exist. This allows to define timers across files without referring to the parent's variable, or to build the tree from configuration:
+
+	calltimer.MustNew("main", nil)
+	dbTimer := calltimer.MustNewUnder("db", "main")
+*/
+func NewUnder(name, parentName string) (*Timer, error) {
+	return defaultRegistry.NewUnder(name, parentName)
+}
+
+/*
+MustNewUnder wraps NewUnder and panics upon error.
+*/
+func MustNewUnder(name, parentName string) *Timer {
+	return defaultRegistry.MustNewUnder(name, parentName)
+}
+
+/*
+NewPath creates the timers in a path such as "main/outer/middle/inner", where each timer is the parent of the next one, and returns the last one. Existing timers along the path are reused, provided that their parent matches the path. The names in the path are separated by PathSeparator.
+*/
+func NewPath(path string) (*Timer, error) {
+	defaultRegistry.mu.Lock()
+	defer defaultRegistry.mu.Unlock()
+
+	var t *Timer
+	for _, name := range strings.Split(path, PathSeparator) {
+		if existing, ok := defaultRegistry.timers[name]; ok {
+			if existing.Parent != t {
+				return nil, fmt.Errorf("timer %q is already defined under a different parent", name)
+			}
+			t = existing
+			continue
+		}
+		var err error
+		if t, err = defaultRegistry.newTimer(name, t); err != nil {
+			return nil, err
+		}
 	}
+	return t, nil
+}
+
+/*
+MustNewPath wraps NewPath and panics upon error. This is handy to quickly build hierarchies:
 
-	t, err := New(name, parent)
+	innerTimer := calltimer.MustNewPath("main/outer/middle/inner")
+*/
+func MustNewPath(path string) *Timer {
+	t, err := NewPath(path)
 	if err != nil {
 		panic(fmt.Sprintf("TIMER PANIC: %v", err))
 	}
 	return t
 }
 
+/*
+Child returns the child of the timer that's named "<timer name>/<name>", using PathSeparator, and creates it in the timer's registry when it doesn't exist yet. The composite name keeps children with the same name under different parents apart. The path of the child (see Path()) is that of the timer, followed by name. This allows per-key timings, e.g. one child per query, without bookkeeping:
+
+	queryTimer := calltimer.MustNew("query", nil)
+	...
+	defer queryTimer.Child(queryName).Start()()
+
+Child is safe to call from concurrent goroutines. It panics when the child can't be created, e.g. when the composite name is already used by a timer under a different parent, like MustNew.
+*/
+func (t *Timer) Child(name string) *Timer {
+	reg := t.registry()
+	reg.mu.Lock()
+	defer reg.mu.Unlock()
+
+	full := t.Name + PathSeparator + name
+	c, ok := reg.timers[full]
+	if ok && c.Parent != t {
+		panic(fmt.Sprintf("TIMER PANIC: timer %q is already defined under a different parent", full))
+	}
+	if !ok {
+		var err error
+		if c, err = reg.newTimer(full, t); err != nil {
+			panic(fmt.Sprintf("TIMER PANIC: %v", err))
+		}
+	}
+	return c
+}
+
+/*
+SetParent moves the timer, with its descendants, under a new parent, for hierarchies that are only known at runtime. A nil parent makes the timer a root. The parent must be in the same registry as the timer, and can't be the timer itself or one of its descendants, as that would create a cycle.
+*/
+func (t *Timer) SetParent(p *Timer) error {
+	reg := t.registry()
+	reg.mu.Lock()
+	defer reg.mu.Unlock()
+
+	if p != nil && p.registry() != reg {
+		return fmt.Errorf("timer %q: parent %q is in a different registry", t.Name, p.Name)
+	}
+	if p != nil && t.isAncestorOf(p) {
+		return fmt.Errorf("timer %q can't be moved under %q, which would create a cycle", t.Name, p.Name)
+	}
+	isT := func(c *Timer) bool { return c == t }
+	if t.Parent == nil {
+		reg.roots = slices.DeleteFunc(reg.roots, isT)
+	} else {
+		t.Parent.Children = slices.DeleteFunc(t.Parent.Children, isT)
+	}
+	t.Parent = p
+	if p == nil {
+		reg.roots = append(reg.roots, t)
+	} else {
+		p.Children = append(p.Children, t)
+	}
+	return nil
+}
+
+// isAncestorOf returns true when t is d itself, or one of d's parents, grandparents and so on. Making d the parent of t would then create a cycle, on which the recursion of reports wouldn't end. The registry must be locked.
+func (t *Timer) isAncestorOf(d *Timer) bool {
+	for a := d; a != nil; a = a.Parent {
+		if a == t {
+			return true
+		}
+	}
+	return false
+}
+
+/*
+Path returns the names of the timer's root, the intermediate parents, and the timer itself, separated by PathSeparator. Children that are named after their parent, such as those of Child(), appear without the parent's prefix, e.g. "query/getUser" rather than "query/query/getUser".
+*/
+func (t *Timer) Path() string {
+	names := []string{t.pathName()}
+	for p := t.Parent; p != nil; p = p.Parent {
+		names = append(names, p.pathName())
+	}
+	slices.Reverse(names)
+	return strings.Join(names, PathSeparator)
+}
+
+// pathName returns the timer's name as it appears in its path: without the prefix of the parent's name and PathSeparator, see Child().
+func (t *Timer) pathName() string {
+	if t.Parent != nil {
+		if name, ok := strings.CutPrefix(t.Name, t.Parent.Name+PathSeparator); ok && name != "" {
+			return name
+		}
+	}
+	return t.Name
+}
+
+/*
+CheckConsistency verifies the parent/child wiring of the timer and of its descendants: a timer's parent must list the timer as a child, and each child must point back to its parent. Timers that are created using New() and friends are always consistent; this catches mistakes in hand-assembled trees, which would otherwise produce garbled reports.
+*/
+func (t *Timer) CheckConsistency() error {
+	if t.Parent != nil && !slices.Contains(t.Parent.Children, t) {
+		return fmt.Errorf("timer %q has parent %q, which doesn't list it as a child", t.Name, t.Parent.Name)
+	}
+	seen := map[*Timer]bool{}
+	var check func(t *Timer) error
+	check = func(t *Timer) error {
+		if seen[t] {
+			return fmt.Errorf("timer %q occurs more than once in the tree", t.Name)
+		}
+		seen[t] = true
+		for _, c := range t.Children {
+			if c.Parent != t {
+				parent := "none"
+				if c.Parent != nil {
+					parent = fmt.Sprintf("%q", c.Parent.Name)
+				}
+				return fmt.Errorf("timer %q lists child %q, whose parent is %s", t.Name, c.Name, parent)
+			}
+			if err := check(c); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+	return check(t)
+}
+
+/*
+ValidateNames checks the names of all timers against a pattern, and returns an error for each name that doesn't match, sorted by name. The pattern should be anchored to check entire names, e.g. `^[a-z]+(\.[a-z]+)*$` for dotted lowercase names. This is typically called in a test or during initialization to enforce naming conventions.
+*/
+func ValidateNames(pattern *regexp.Regexp) []error {
+	return defaultRegistry.ValidateNames(pattern)
+}
+
+/*
+ValidateNames is like the package-level ValidateNames(), but checks the names of the timers of this registry.
+*/
+func (r *Registry) ValidateNames(pattern *regexp.Regexp) []error {
+	var errs []error
+	for _, name := range r.Names() {
+		if !pattern.MatchString(name) {
+			errs = append(errs, fmt.Errorf("timer %q doesn't match %q", name, pattern))
+		}
+	}
+	return errs
+}
+
+/*
+SetClock sets the time source of the timer, which LogSince() and friends use to determine the current time. Passing nil restores the default, which is the clock of the timer's registry (see Registry.SetClock()) or else DefaultClock. The clock should be set before the timer is in use, or using the option WithClock(). A different clock is useful to drive timers using e.g. virtual time in a simulation. Starting times should then be taken from the same clock, which is available as Now():
+
+	defer simTimer.LogSince(simTimer.Now())
+
+A function can serve as clock using ClockFunc.
+*/
+func (t *Timer) SetClock(clock Clock) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	t.clock = clock
+}
+
+/*
+SetActive switches recording on or off for the timer and its descendants, without affecting other timers. This allows to silence the instrumentation of e.g. a hot loop while keeping the rest. Timers that are created under an inactive parent are inactive, too. The global Active takes precedence: when it's false, nothing is recorded. Reports show inactive timers only when they have activity from before they were switched off.
+*/
+func (t *Timer) SetActive(active bool) {
+	reg := t.registry()
+	reg.mu.Lock()
+	defer reg.mu.Unlock()
+
+	var walk func(t *Timer)
+	walk = func(t *Timer) {
+		t.mu.Lock()
+		t.paused = !active
+		t.mu.Unlock()
+		for _, c := range t.Children {
+			walk(c)
+		}
+	}
+	walk(t)
+}
+
+// recording returns true when the timer isn't switched off by SetEnabledPattern() or SetActive(). The timer must be locked.
+func (t *Timer) recording() bool {
+	return !t.disabled && !t.paused
+}
+
+/*
+Now returns the current time according to the timer's clock, see SetClock().
+*/
+func (t *Timer) Now() time.Time {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	return t.now()
+}
+
+// now returns the current time according to the timer's clock. Unlike Now(), it may be called while the timer is locked.
+func (t *Timer) now() time.Time {
+	if now, ok := t.clockNow(); ok {
+		return now
+	}
+	return DefaultClock.Now()
+}
+
+// clockNow returns the current time according to the clock of the timer or else of its registry, and false when neither has a clock, so that DefaultClock applies. The timer must be locked.
+func (t *Timer) clockNow() (time.Time, bool) {
+	if t.clock != nil {
+		return t.clock.Now(), true
+	}
+	if t.reg != nil {
+		if c := t.reg.clock.Load(); c != nil {
+			return (*c).Now(), true
+		}
+	}
+	return time.Time{}, false
+}
+
 /*
 LogDuration adds the passed-in duration to the timer's TotalElapsed and increments the timer's CalledTimes counter. It is probably not that useful, given that LogSince() is more intuitive.
 */
@@ -119,8 +565,86 @@ func (t *Timer) LogDuration(d time.Duration) {
 	t.mu.Lock()
 	defer t.mu.Unlock()
 
-	t.TotalElapsed += d
-	t.CalledTimes++
+	t.record(d, 1, nil)
+}
+
+/*
+LogDurationWeighted is like LogDuration, but for an invocation that handled a batch of items: the duration is added to the timer's TotalElapsed, and the weight (the number of items) is added to CalledTimes. The reported average is then the time per item rather than per invocation, and MaxElapsed is the slowest time per item. For example:
+
+	start := time.Now()
+	insertRows(rows)
+	insertTimer.LogDurationWeighted(time.Since(start), len(rows))
+
+Weights below 1 count as 1.
+*/
+func (t *Timer) LogDurationWeighted(d time.Duration, weight int) {
+	if !Active {
+		return
+	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	t.record(d, max(weight, 1), nil)
+}
+
+/*
+Clamped returns the number of negative durations that were logged on the timer, e.g. by LogSince() with a start time from the future due to clock skew. Such durations are recorded as zero, so that TotalElapsed and the average stay meaningful; the call still counts.
+*/
+func (t *Timer) Clamped() int {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	return t.clamped
+}
+
+/*
+LogDurationN is the same as LogDurationWeighted: d is added to TotalElapsed, but CalledTimes grows by n, so that the average is the time per item. LogDuration instead counts a single call. For example, for a batch insert of 500 rows:
+
+	insertTimer.LogDurationN(time.Since(start), 500) // average is the time per row
+*/
+func (t *Timer) LogDurationN(d time.Duration, n int) {
+	t.LogDurationWeighted(d, n)
+}
+
+// record adds the duration of an invocation that handled a number of items to the timer, which must be locked, and returns the duration as recorded, i.e. clamped at zero. The fields are kept when the duration per item is the new maximum.
+func (t *Timer) record(d time.Duration, items int, fields map[string]string) time.Duration {
+	if !t.recording() {
+		return d
+	}
+	if d < 0 {
+		// A start time from the future, e.g. due to clock skew
+		d = 0
+		t.clamped++
+	}
+	t.LastElapsed = d
+	scale := 1
+	if t.sampling > 1 {
+		t.unsampled++
+		if t.unsampled < t.sampling {
+			return d
+		}
+		t.unsampled = 0
+		scale = t.sampling
+	}
+	calls := items * scale
+	perItem := d / time.Duration(items)
+	t.TotalElapsed += d * time.Duration(scale)
+	t.CalledTimes += calls
+	if t.bucketCounts != nil {
+		t.bucketCounts[t.bucket(perItem)] += calls
+	}
+	if t.sketch != nil {
+		t.sketch.add(perItem, calls)
+	}
+	if t.samples != nil {
+		t.samples = append(t.samples, perItem)
+	}
+	t.addVariance(perItem, calls)
+	if perItem > t.MaxElapsed || t.CalledTimes == calls {
+		t.MaxElapsed = perItem
+		t.slowestFields = maps.Clone(fields)
+	}
+	return d
 }
 
 /*
@@ -146,199 +670,455 @@ To limit what's tracked, the following can be used:
 
 		doMoreNotInterestingStuff()
 	}
+
+LogSince returns immediately when Active is false, but the caller's time.Now() still reads the clock, which is the larger part of the cost. In hot code, Start() avoids reading the clock when Active is false.
 */
 func (t *Timer) LogSince(tstart time.Time) {
 	if !Active {
 		return
 	}
 
-	t.LogDuration(time.Since(tstart))
+	t.LogDuration(t.now().Sub(tstart))
 }
 
 /*
-ReportAll sends reports of all root timers (i.e., those which don't have a parent) to the passed-in io.Writer.
-
-Example:
+Start takes the current time and returns a function that logs the duration since then. This reads more clearly than LogSince() in a defer:
 
-	r1 := calltimer.MustNew("r1", nil)
-	c1 := calltimer.MustNew("c1", r1)
-	r2 := calltimer.MustNew("r2", nil)
+	func myFunc() {
+		defer myFuncTimer.Start()()
+		doSomeInterestingStuff()
+	}
 
-	// This reports on root timer "r1" together with its child timer "c1",
-	// and on the other root timer "r2". Root timers without activity are
-	// not reported.
-	calltimer.ReportAll()
+The returned function logs only once; further calls are ignored. Between Start and the call of the returned function, the call is in flight, see InFlight().
 */
-func ReportAll(wr io.Writer) {
+func (t *Timer) Start() func() {
 	if !Active {
-		return
+		return func() {}
 	}
-	mu.Lock()
-	defer mu.Unlock()
 
-	rLen := &reportLen{}
-	for _, r := range roots {
-		r.calculateLengths(rLen, 0)
-	}
+	t.mu.Lock()
+	t.addInFlight(1)
+	tstart := t.now()
+	t.mu.Unlock()
 
-	for _, r := range roots {
-		r.reportWithFormatting(wr, rLen)
+	var once sync.Once
+	return func() {
+		once.Do(func() {
+			t.mu.Lock()
+			t.addInFlight(-1)
+			t.mu.Unlock()
+			t.LogSince(tstart)
+		})
 	}
 }
 
 /*
-Report sends a report for the applicable timer to the passed-in io.Writer. For example:
-
-		main        total 326.627542ms in  1 calls, avg 326.627542ms
-	  	  outer     total  326.62675ms in  2 calls, avg 163.313375ms
-	        middle  total 260.368249ms in  6 calls, avg  43.394708ms
-	          inner total 260.350961ms in 24 calls, avg  10.847956ms
+InFlight returns the number of calls of the timer that are in progress: started using Start() or Enter(), but not yet stopped or exited. A number that keeps growing indicates calls that are stuck.
+*/
+func (t *Timer) InFlight() int {
+	t.mu.Lock()
+	defer t.mu.Unlock()
 
-In this case, there is a one-to-one parent/child relationship: main has one child outer, which has one child middle, which has one child inner.
+	return t.inFlight
+}
 
-Timers that have no logged activity are not reported.
+/*
+PeakInFlight returns the highest number of calls of the timer that were in progress at the same time, see InFlight(). This is the peak concurrency of e.g. a function that's called from parallel goroutines.
 */
-func (t *Timer) Report(wr io.Writer) {
-	if !Active || !t.hasActivity() {
-		return
-	}
+func (t *Timer) PeakInFlight() int {
 	t.mu.Lock()
 	defer t.mu.Unlock()
 
-	rLen := &reportLen{}
-	t.calculateLengths(rLen, 0)
+	return t.peakInFlight
+}
+
+// addInFlight adds n to the number of calls in flight, and updates the peak. The timer must be locked.
+func (t *Timer) addInFlight(n int) {
+	t.inFlight += n
+	t.peakInFlight = max(t.peakInFlight, t.inFlight)
+}
+
+/*
+Time calls fn and logs the duration of the call, for inline timing of small scopes:
+
+	dbTimer.Time(func() {
+		rows = query(db)
+	})
+
+See TimeVal() for functions that return a value.
+*/
+func (t *Timer) Time(fn func()) {
+	defer t.Start()()
+	fn()
+}
+
+/*
+TimeVal is like Time, but for a function that returns a value, which is passed on:
 
-	t.report(0, rLen, wr)
+	rows := calltimer.TimeVal(dbTimer, func() []Row { return query(db) })
+*/
+func TimeVal[T any](t *Timer, fn func() T) T {
+	defer t.Start()()
+	return fn()
 }
 
-func (t *Timer) reportWithFormatting(wr io.Writer, rLen *reportLen) {
-	if !t.hasActivity() {
+/*
+Enter and Exit track how long a shared resource is busy, when it's used by overlapping goroutines. Enter marks the start of a use, and Exit its end. Each Exit counts as a call, but TotalElapsed only grows by the wall-clock time during which at least one use was in progress. Summing the durations of overlapping uses would count that time several times. For example:
+
+	func query() {
+		dbTimer.Enter()
+		defer dbTimer.Exit()
+		...
+	}
+
+Enter and Exit must be balanced, and shouldn't be combined with LogSince() or LogDuration() on the same timer.
+*/
+func (t *Timer) Enter() {
+	if !Active {
 		return
 	}
-	t.report(0, rLen, wr)
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if t.entered == 0 {
+		t.busySince = t.now()
+	}
+	t.entered++
+	t.addInFlight(1)
 }
 
-func (t *Timer) calculateLengths(lengths *reportLen, level int) {
-	if !t.hasActivity() {
+// Exit marks the end of a use that was started by Enter.
+func (t *Timer) Exit() {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if t.entered == 0 {
 		return
 	}
-	lengths.leaderLen = max(lengths.leaderLen, level*2+len(t.Name))
-	lengths.totalLen = max(lengths.totalLen, len(fmt.Sprintf("%v", t.TotalElapsed)))
-	lengths.callsLen = max(lengths.callsLen, len(fmt.Sprintf("%v", t.CalledTimes)))
-	if t.CalledTimes > 0 {
-		lengths.avgLen = max(lengths.avgLen,
-			len(fmt.Sprintf("%v", t.TotalElapsed/time.Duration(t.CalledTimes))))
+	t.entered--
+	t.addInFlight(-1)
+	if !Active || !t.recording() {
+		return
 	}
-	for _, c := range t.Children {
-		c.calculateLengths(lengths, level+1)
+	t.CalledTimes++
+	if t.entered == 0 {
+		t.TotalElapsed += t.now().Sub(t.busySince)
 	}
 }
 
-func (t *Timer) report(lev int, rLen *reportLen, wr io.Writer) {
-	switch OutputFormat {
-	case Table:
-		t.reportTable(lev, rLen, wr)
-	case PlainText:
-		t.reportPlainText(lev, rLen, wr)
-	case CSV:
-		t.reportCSV(lev, wr)
+/*
+Reset clears the statistics of the timer, as if it was never called. Its place in the timer tree is kept. This allows long-running programs to report per time window:
+
+	for range time.Tick(time.Hour) {
+		calltimer.ReportAll(os.Stderr)
+		calltimer.ResetAll()
+	}
+*/
+func (t *Timer) Reset() {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	t.TotalElapsed = 0
+	t.CalledTimes = 0
+	t.MaxElapsed = 0
+	t.LastElapsed = 0
+	t.slowestFields = nil
+	t.unsampled = 0
+	t.reportedCalls = 0
+	for i := range t.bucketCounts {
+		t.bucketCounts[i] = 0
+	}
+	if t.sketch != nil {
+		t.sketch = newSketch()
 	}
+	if t.samples != nil {
+		t.samples = []time.Duration{}
+	}
+	t.tagged = nil
+	t.varCount, t.mean, t.m2 = 0, 0, 0
+	t.peakInFlight = t.inFlight
+	t.clamped = 0
 }
 
-func (t *Timer) reportTable(lev int, rLen *reportLen, wr io.Writer) {
-	ruler := func(rLen *reportLen) {
-		fmt.Fprint(wr, "+")
-		for i := 0; i < rLen.leaderLen+2; i++ {
-			fmt.Fprint(wr, "-")
-		}
-		fmt.Fprint(wr, "+")
-		for i := 0; i < rLen.totalLen+2; i++ {
-			fmt.Fprint(wr, "-")
-		}
-		fmt.Fprint(wr, "+")
-		for i := 0; i < rLen.callsLen+2; i++ {
-			fmt.Fprint(wr, "-")
-		}
-		fmt.Fprint(wr, "+")
-		for i := 0; i < rLen.avgLen+2; i++ {
-			fmt.Fprint(wr, "-")
+/*
+ResetAll resets all timers, see Reset().
+*/
+func ResetAll() {
+	defaultRegistry.ResetAll()
+}
+
+/*
+ResetAll is like the package-level ResetAll(), but resets the timers of this registry.
+*/
+func (r *Registry) ResetAll() {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	var walk func(ts []*Timer)
+	walk = func(ts []*Timer) {
+		for _, t := range ts {
+			t.Reset()
+			walk(t.Children)
 		}
-		fmt.Fprintln(wr, "+")
 	}
-	if lev == 0 {
-		rLen.leaderLen = max(rLen.leaderLen, len(leaderLabel))
-		rLen.totalLen = max(rLen.totalLen, len(totalLabel))
-		rLen.callsLen = max(rLen.callsLen, len(callsLabel))
-		rLen.avgLen = max(rLen.avgLen, len(avgLabel))
+	walk(r.roots)
+}
 
-		ruler(rLen)
-		fmt.Fprintf(wr, "| %-*s | %*s | %*s | %*s |\n",
-			rLen.leaderLen, leaderLabel,
-			rLen.totalLen, totalLabel,
-			rLen.callsLen, callsLabel,
-			rLen.avgLen, avgLabel)
-		ruler(rLen)
-	}
-	fmt.Fprint(wr, "| ")
-	for i := 0; i < lev; i++ {
-		fmt.Fprint(wr, "  ")
+/*
+Remove unregisters the timer with the passed-in name, so that the name can be reused, e.g. by a timer per job in a long-running process. The timer is detached from its parent, and no longer reported. A timer that still has children can't be removed, as that would orphan them; they must be removed first.
+*/
+func Remove(name string) error {
+	return defaultRegistry.Remove(name)
+}
+
+/*
+Names returns the names of all registered timers, sorted. This shows what's defined, e.g. to find out where a name clash comes from.
+*/
+func Names() []string {
+	return defaultRegistry.Names()
+}
+
+/*
+Get returns the registered timer with the passed-in name. The boolean is false when there is no such timer.
+*/
+func Get(name string) (*Timer, bool) {
+	return defaultRegistry.Get(name)
+}
+
+/*
+ResetRegistry forgets all timers, so that names can be defined again using New(). This is meant for test isolation, e.g. in table-driven tests that define the same timers per test case. Existing timers become orphaned: they can still be used, but they are no longer reported by ReportAll() and their names may be taken by new timers.
+*/
+func ResetRegistry() {
+	defaultRegistry.mu.Lock()
+	defer defaultRegistry.mu.Unlock()
+
+	defaultRegistry.timers = map[string]*Timer{}
+	defaultRegistry.roots = []*Timer{}
+}
+
+/*
+LogSinceMulti adds the duration since a given start to several timers, for a single span that counts toward several categories. The end of the span is determined by the clock of each timer (see SetClock()). DefaultClock is read only once, so that the timers that use it receive the same value:
+
+	func handle() {
+		defer calltimer.LogSinceMulti(time.Now(), networkTimer, requestTimer)
+		...
 	}
-	fmt.Fprint(wr, t.Name)
-	for printed := lev*2 + len(t.Name); printed <= rLen.leaderLen; printed++ {
-		fmt.Fprint(wr, " ")
+*/
+func LogSinceMulti(tstart time.Time, ts ...*Timer) {
+	if !Active {
+		return
 	}
 
-	var avg string
-	if t.CalledTimes > 0 {
-		avg = fmt.Sprintf("%v", t.TotalElapsed/time.Duration(t.CalledTimes))
+	var defaultEnd time.Time // End according to DefaultClock, zero until it's read
+	for _, t := range ts {
+		t.mu.Lock()
+		end, ok := t.clockNow()
+		t.mu.Unlock()
+		if !ok {
+			if defaultEnd.IsZero() {
+				defaultEnd = DefaultClock.Now()
+			}
+			end = defaultEnd
+		}
+		t.LogDuration(end.Sub(tstart))
 	}
-	fmt.Fprintf(wr, "| %*v | %*v | %*v |\n",
-		rLen.totalLen, t.TotalElapsed,
-		rLen.callsLen, t.CalledTimes,
-		rLen.avgLen, avg)
+}
 
-	for _, c := range t.Children {
-		c.reportTable(lev+1, rLen, wr)
-	}
+/*
+Once calls fn and records its duration, but only the first time that Once is invoked on the timer; later invocations don't do anything. This is handy to time lazy initialization:
 
-	if lev == 0 {
-		ruler(rLen)
+	func getConfig() *Config {
+		configTimer.Once(func() {
+			config = loadConfig()
+		})
+		return config
 	}
+*/
+func (t *Timer) Once(fn func()) {
+	t.once.Do(func() {
+		start := t.now()
+		fn()
+		t.LogSince(start)
+	})
 }
 
-func (t *Timer) reportPlainText(lev int, rLen *reportLen, wr io.Writer) {
-	for i := 0; i < lev; i++ {
-		fmt.Fprint(wr, "  ")
+/*
+LogSinceCtx is like LogSince, but additionally accepts key/value context of the call. When the call turns out to be the slowest one so far, the fields are retained and can be retrieved using SlowestFields(). For example:
+
+	func handle(req *Request) {
+		defer handleTimer.LogSinceCtx(time.Now(), map[string]string{"request-id": req.ID})
+		...
 	}
-	fmt.Fprint(wr, t.Name)
-	for printed := lev*2 + len(t.Name); printed <= rLen.leaderLen; printed++ {
-		fmt.Fprint(wr, " ")
+*/
+func (t *Timer) LogSinceCtx(tstart time.Time, fields map[string]string) {
+	if !Active {
+		return
 	}
-	fmt.Fprintf(wr, "total %*v in %*v calls",
-		rLen.totalLen, t.TotalElapsed, rLen.callsLen, t.CalledTimes)
-	if t.CalledTimes > 0 {
-		fmt.Fprintf(wr, ", avg %*v",
-			rLen.avgLen, t.TotalElapsed/time.Duration(t.CalledTimes))
+	d := t.now().Sub(tstart)
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	t.record(d, 1, fields)
+}
+
+/*
+SlowestFields returns a copy of the fields that were passed to LogSinceCtx() for the slowest invocation, or nil when the slowest invocation didn't provide fields.
+*/
+func (t *Timer) SlowestFields() map[string]string {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	return maps.Clone(t.slowestFields)
+}
+
+/*
+Average returns the average duration per call of the timer, or 0 when it wasn't called.
+*/
+func (t *Timer) Average() time.Duration {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	return average(t.TotalElapsed, t.CalledTimes)
+}
+
+// average returns total divided by calls, or 0 when there were no calls.
+func average(total time.Duration, calls int) time.Duration {
+	if calls <= 0 {
+		return 0
 	}
-	fmt.Fprintln(wr)
+	return total / time.Duration(calls)
+}
 
-	for _, c := range t.Children {
-		c.report(lev+1, rLen, wr)
+/*
+FilterTimers walks all timers, roots first and then their children, and returns the ones for which pred returns true. For example, to find the timers that are expensive per call and called often:
+
+	hot := calltimer.FilterTimers(func(t *calltimer.Timer, s calltimer.TimerStats) bool {
+		return s.CalledTimes > 1000 && s.Average() > 100*time.Millisecond
+	})
+
+The predicate receives each timer and a consistent copy of its statistics, which are read without racing against concurrent logging. Like Walk(), it's called after the tree is copied and the registry is unlocked, so it may call any function of the package, including ones that lock the registry, such as Snapshot(), Report() or New(). Timers that are created meanwhile aren't visited.
+*/
+func FilterTimers(pred func(t *Timer, s TimerStats) bool) []*Timer {
+	return defaultRegistry.FilterTimers(pred)
+}
+
+/*
+FilterTimers is like the package-level FilterTimers(), but filters the timers of this registry.
+*/
+func (r *Registry) FilterTimers(pred func(t *Timer, s TimerStats) bool) []*Timer {
+	r.mu.Lock()
+	es := walkEntries(r.roots, 0, nil)
+	r.mu.Unlock()
+
+	var out []*Timer
+	for _, e := range es {
+		if pred(e.t, e.stats) {
+			out = append(out, e.t)
+		}
 	}
+	return out
+}
+
+/*
+Walk visits all timers depth-first: each root, then its descendants, passing each timer, a consistent copy of its statistics, and its depth, which is 0 for roots. When fn returns false, the children of the timer are skipped. This is the basis for custom reports, e.g.:
+
+	calltimer.Walk(func(t *calltimer.Timer, s calltimer.TimerStats, depth int) bool {
+		fmt.Printf("%s%s: %v\n", strings.Repeat("  ", depth), t.Name, s.TotalElapsed)
+		return s.TotalElapsed > time.Millisecond // don't descend into fast timers
+	})
+
+The tree and the statistics are copied first, and fn is called afterwards, without holding any lock. So fn may call any function of the package, e.g. Snapshot(), Report() or Child(). Timers that are created or moved meanwhile aren't visited, or are visited at their earlier place.
+*/
+func Walk(fn func(t *Timer, s TimerStats, depth int) bool) {
+	defaultRegistry.Walk(fn)
+}
+
+/*
+Walk is like the package-level Walk(), but visits the timers of this registry.
+*/
+func (r *Registry) Walk(fn func(t *Timer, s TimerStats, depth int) bool) {
+	r.mu.Lock()
+	es := walkEntries(r.roots, 0, nil)
+	r.mu.Unlock()
+
+	visit(es, fn)
+}
+
+/*
+Walk is like the package-level Walk, but visits the timer and its descendants. The depth of the timer itself is 0.
+*/
+func (t *Timer) Walk(fn func(t *Timer, s TimerStats, depth int) bool) {
+	reg := t.registry()
+	reg.mu.Lock()
+	es := walkEntries([]*Timer{t}, 0, nil)
+	reg.mu.Unlock()
+
+	visit(es, fn)
+}
+
+// walkEntry is a timer that Walk visits.
+type walkEntry struct {
+	t     *Timer
+	stats TimerStats
+	depth int
 }
 
-func (t *Timer) reportCSV(lev int, wr io.Writer) {
-	if lev == 0 {
-		fmt.Fprintln(wr, "Timer;Total;Calls;Average")
+// walkEntries appends the timers and their descendants to es, depth-first, and returns the result. The registry must be locked.
+func walkEntries(ts []*Timer, depth int, es []walkEntry) []walkEntry {
+	for _, t := range ts {
+		es = append(es, walkEntry{t: t, stats: t.stats(), depth: depth})
+		es = walkEntries(t.Children, depth+1, es)
 	}
-	fmt.Fprintf(wr, "%v;%v;%v;", t.Name, t.TotalElapsed, t.CalledTimes)
-	if t.CalledTimes > 0 {
-		fmt.Fprintf(wr, "%v", t.TotalElapsed/time.Duration(t.CalledTimes))
+	return es
+}
+
+// visit calls fn for the entries, skipping the descendants of an entry for which fn returns false.
+func visit(es []walkEntry, fn func(t *Timer, s TimerStats, depth int) bool) {
+	skip := -1 // Descendants of an entry at this depth are skipped, -1 when none are
+	for _, e := range es {
+		if skip >= 0 && e.depth > skip {
+			continue
+		}
+		skip = -1
+		if !fn(e.t, e.stats, e.depth) {
+			skip = e.depth
+		}
 	}
-	fmt.Fprintln(wr)
+}
 
-	for _, c := range t.Children {
-		c.reportCSV(lev+1, wr)
+// copyStats returns a copy of the timer, taken under the timer's lock. The copy shares Parent and Children with the original.
+func (t *Timer) copyStats() *Timer {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	return &Timer{
+		Name:          t.Name,
+		TotalElapsed:  t.TotalElapsed,
+		CalledTimes:   t.CalledTimes,
+		MaxElapsed:    t.MaxElapsed,
+		LastElapsed:   t.LastElapsed,
+		Budget:        t.Budget,
+		Tags:          t.Tags,
+		Parent:        t.Parent,
+		Children:      t.Children,
+		slowestFields: t.slowestFields,
+		sampling:      t.sampling,
+		unsampled:     t.unsampled,
+		buckets:       t.buckets,
+		bucketCounts:  slices.Clone(t.bucketCounts),
+		sketch:        t.sketch.clone(),
+		samples:       slices.Clone(t.samples),
+		tagged:        maps.Clone(t.tagged),
+		reportedCalls: t.reportedCalls,
+		clock:         t.clock,
+		windowStart:   t.windowStart,
+		windowEnd:     t.windowEnd,
+		varCount:      t.varCount,
+		mean:          t.mean,
+		m2:            t.m2,
+		inFlight:      t.inFlight,
+		peakInFlight:  t.peakInFlight,
+		clamped:       t.clamped,
 	}
 }
 
@@ -348,5 +1128,5 @@ func (t *Timer) hasActivity() bool {
 			return true
 		}
 	}
-	return t.TotalElapsed > 0
+	return t.hasOwnActivity()
 }
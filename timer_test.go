@@ -1,9 +1,1388 @@
 package calltimer
 
 import (
+	"bytes"
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"regexp"
+	"strings"
+	"sync"
+	"sync/atomic"
 	"testing"
+	"time"
+	"unicode/utf8"
 )
 
 func TestAll(t *testing.T) {
 	// TODO: Add tests
 }
+
+// resetGlobals clears the package-level registry so that tests can reuse timer names.
+func resetGlobals() {
+	ResetRegistry()
+	defaultRegistry.mu.Lock()
+	defer defaultRegistry.mu.Unlock()
+	defaultRegistry.archives = map[string]*Timer{}
+}
+
+// failingWriter fails every write.
+type failingWriter struct{}
+
+func (failingWriter) Write(p []byte) (int, error) {
+	return 0, errors.New("write failed")
+}
+
+func TestReportBuffered(t *testing.T) {
+	resetGlobals()
+	defer func() { ReportBuffered = false }()
+
+	r := MustNew("root", nil)
+	c := MustNew("child", r)
+	r.LogDuration(time.Second)
+	c.LogDuration(time.Millisecond)
+
+	var unbuffered, buffered bytes.Buffer
+	if err := ReportAll(&unbuffered); err != nil {
+		t.Fatalf("ReportAll() = %v, want nil", err)
+	}
+	ReportBuffered = true
+	if err := ReportAll(&buffered); err != nil {
+		t.Fatalf("ReportAll() = %v, want nil", err)
+	}
+	if unbuffered.String() != buffered.String() {
+		t.Errorf("buffered report:\n%s\ndiffers from unbuffered report:\n%s", buffered.String(), unbuffered.String())
+	}
+
+	if err := ReportAll(failingWriter{}); err == nil {
+		t.Error("ReportAll(failingWriter) = nil, want error")
+	}
+	if err := r.Report(failingWriter{}); err == nil {
+		t.Error("Report(failingWriter) = nil, want error")
+	}
+}
+
+func TestLogSinceCtx(t *testing.T) {
+	resetGlobals()
+
+	tm := MustNew("ctx", nil)
+	tm.LogSinceCtx(time.Now().Add(-time.Second), map[string]string{"id": "slow"})
+	tm.LogSinceCtx(time.Now(), map[string]string{"id": "fast"})
+	if got := tm.SlowestFields()["id"]; got != "slow" {
+		t.Errorf("SlowestFields()[id] = %q, want %q", got, "slow")
+	}
+	if tm.MaxElapsed < time.Second {
+		t.Errorf("MaxElapsed = %v, want at least 1s", tm.MaxElapsed)
+	}
+
+	// A slower invocation without fields drops the previous context.
+	tm.LogDuration(time.Hour)
+	if got := tm.SlowestFields(); got != nil {
+		t.Errorf("SlowestFields() = %v, want nil", got)
+	}
+}
+
+func TestCSVEscaping(t *testing.T) {
+	resetGlobals()
+	defer func() { OutputFormat = Table }()
+
+	tm := MustNew(`a;b"c`, nil)
+	tm.LogDuration(time.Second)
+
+	OutputFormat = CSV
+	var b bytes.Buffer
+	ReportAll(&b)
+	want := "Timer;Total;Calls;Average\n\"a;b\"\"c\";1s;1;1s\n"
+	if b.String() != want {
+		t.Errorf("ReportAll() = %q, want %q", b.String(), want)
+	}
+
+	defer func() { CSVDelimiter = ';' }()
+	CSVDelimiter = ','
+	MustNew("x,\ny", tm).LogDuration(time.Second)
+	b.Reset()
+	ReportAll(&b)
+	rd := csv.NewReader(&b)
+	rd.FieldsPerRecord = -1
+	recs, err := rd.ReadAll()
+	if err != nil || len(recs) != 3 || recs[1][0] != `a;b"c` || recs[2][0] != "x,\ny" {
+		t.Errorf("ReportAll() with delimiter ',' parsed as %q, %v", recs, err)
+	}
+
+	for _, d := range []rune{'"', '\n', utf8.RuneError} {
+		CSVDelimiter = d
+		if err := ReportAll(io.Discard); err == nil {
+			t.Errorf("ReportAll() with delimiter %q = nil, want error", d)
+		}
+		if err := tm.Report(io.Discard); err == nil {
+			t.Errorf("Report() with delimiter %q = nil, want error", d)
+		}
+	}
+}
+
+func TestTSV(t *testing.T) {
+	resetGlobals()
+	defer func() { OutputFormat = Table }()
+
+	r := MustNew("root", nil)
+	MustNew("a\tb\nc", r).LogDuration(time.Second)
+	r.LogDuration(2 * time.Second)
+
+	OutputFormat = TSV
+	var b bytes.Buffer
+	ReportAll(&b)
+	want := "Timer\tTotal\tCalls\tAverage\nroot\t2s\t1\t2s\na b c\t1s\t1\t1s\n"
+	if b.String() != want {
+		t.Errorf("ReportAll() = %q, want %q", b.String(), want)
+	}
+}
+
+func TestFilterTimers(t *testing.T) {
+	resetGlobals()
+
+	r := MustNew("root", nil)
+	fast := MustNew("fast", r)
+	slow := MustNew("slow", r)
+	r.LogDuration(time.Second)
+	fast.LogDuration(time.Millisecond)
+	slow.LogDuration(500 * time.Millisecond)
+
+	got := FilterTimers(func(t *Timer, s TimerStats) bool {
+		return s.TotalElapsed >= 100*time.Millisecond
+	})
+	if len(got) != 2 || got[0] != r || got[1] != slow {
+		t.Errorf("FilterTimers() = %v, want [root slow]", got)
+	}
+
+	// The predicate may lock the registry.
+	got = FilterTimers(func(t *Timer, s TimerStats) bool {
+		return len(t.Snapshot().Children) > 0
+	})
+	if len(got) != 1 || got[0] != r {
+		t.Errorf("FilterTimers() with Snapshot() = %v, want [root]", got)
+	}
+}
+
+func TestReportMaxRows(t *testing.T) {
+	resetGlobals()
+	defer func() { OutputFormat, ReportMaxRows = Table, 0 }()
+
+	r := MustNew("root", nil)
+	for _, name := range []string{"a", "b", "c"} {
+		MustNew(name, r).LogDuration(time.Second)
+	}
+	MustNew("idle", r)
+	r.LogDuration(time.Second)
+
+	OutputFormat = CSV
+	ReportMaxRows = 2
+	var b bytes.Buffer
+	ReportAll(&b)
+	want := "Timer;Total;Calls;Average\nroot;1s;1;1s\na;1s;1;1s\n… truncated, 2 more timers\n"
+	if b.String() != want {
+		t.Errorf("ReportAll() = %q, want %q", b.String(), want)
+	}
+}
+
+func TestReportColor(t *testing.T) {
+	resetGlobals()
+	defer func(isTerm func(io.Writer) bool) {
+		OutputFormat, ReportColor, isTerminal = Table, false, isTerm
+	}(isTerminal)
+
+	r := MustNew("root", nil)
+	MustNew("hot", r).LogDuration(60 * time.Millisecond)
+	MustNew("warm", r).LogDuration(30 * time.Millisecond)
+	MustNew("cold", r).LogDuration(10 * time.Millisecond)
+	r.LogDuration(100 * time.Millisecond)
+
+	OutputFormat = PlainText
+	ReportColor = true
+	var b bytes.Buffer
+	ReportAll(&b)
+	if strings.Contains(b.String(), "\x1b[") {
+		t.Errorf("ReportAll() to a non-terminal has color codes:\n%s", b.String())
+	}
+
+	isTerminal = func(io.Writer) bool { return true }
+	b.Reset()
+	ReportAll(&b)
+	lines := strings.Split(b.String(), "\n")
+	for i, want := range []string{"", string(hot), string(warm), ""} {
+		if got := strings.Contains(lines[i], "\x1b["); got != (want != "") || !strings.Contains(lines[i], want) {
+			t.Errorf("line %q: want color %q", lines[i], want)
+		}
+	}
+}
+
+func TestReportChanged(t *testing.T) {
+	resetGlobals()
+	defer func() { OutputFormat = Table }()
+	OutputFormat = CSV
+
+	r := MustNew("root", nil)
+	a := MustNew("a", r)
+	b := MustNew("b", r)
+	r.LogDuration(time.Second)
+	a.LogDuration(time.Second)
+	b.LogDuration(time.Second)
+
+	for _, test := range []struct {
+		log  *Timer
+		want string
+	}{
+		{nil, "Timer;Total;Calls;Average\nroot;1s;1;1s\na;1s;1;1s\nb;1s;1;1s\n"},
+		{b, "Timer;Total;Calls;Average\nroot;1s;1;1s\nb;2s;2;1s\n"},
+		{nil, ""},
+	} {
+		if test.log != nil {
+			test.log.LogDuration(time.Second)
+		}
+		var buf bytes.Buffer
+		ReportChanged(&buf)
+		if buf.String() != test.want {
+			t.Errorf("ReportChanged() = %q, want %q", buf.String(), test.want)
+		}
+	}
+}
+
+// hookWriter calls hook before the first write.
+type hookWriter struct {
+	bytes.Buffer
+	hook func()
+}
+
+func (w *hookWriter) Write(p []byte) (int, error) {
+	if w.hook != nil {
+		w.hook()
+		w.hook = nil
+	}
+	return w.Buffer.Write(p)
+}
+
+func TestReportChangedDuringReport(t *testing.T) {
+	resetGlobals()
+	defer func() { OutputFormat = Table }()
+	OutputFormat = JSONLines
+
+	r := MustNew("root", nil)
+	r.LogDuration(time.Second)
+
+	// The call that is logged while the report is written isn't in the report, so the next report must show it.
+	w := &hookWriter{hook: func() { r.LogDuration(time.Second) }}
+	ReportChanged(w)
+	if !strings.Contains(w.String(), `"calls":1`) {
+		t.Errorf("ReportChanged() = %q, want 1 call", w.String())
+	}
+	var b bytes.Buffer
+	ReportChanged(&b)
+	if !strings.Contains(b.String(), `"calls":2`) {
+		t.Errorf("next ReportChanged() = %q, want the call that was logged during the previous report", b.String())
+	}
+}
+
+func TestReportLegend(t *testing.T) {
+	resetGlobals()
+	defer func() { OutputFormat, ReportLegend = Table, false }()
+
+	MustNew("root", nil).LogDuration(time.Second)
+	ReportLegend = true
+	for f, want := range map[Format]bool{Table: true, PlainText: true, CSV: false} {
+		OutputFormat = f
+		var b bytes.Buffer
+		ReportAll(&b)
+		if got := strings.Contains(b.String(), "Legend: "); got != want {
+			t.Errorf("format %v: legend shown = %v, want %v", f, got, want)
+		}
+	}
+}
+
+func TestNewPath(t *testing.T) {
+	resetGlobals()
+
+	inner := MustNewPath("main/outer/inner")
+	if got := inner.Path(); got != "main/outer/inner" {
+		t.Errorf("Path() = %q, want %q", got, "main/outer/inner")
+	}
+	other := MustNewPath("main/outer/other")
+	if other.Parent != inner.Parent {
+		t.Errorf("NewPath() didn't reuse the existing parent")
+	}
+	if _, err := NewPath("main/inner"); err == nil {
+		t.Error("NewPath() with a mismatching parent succeeded, want error")
+	}
+	if _, err := NewPath("main//x"); err == nil {
+		t.Error("NewPath() with an empty name succeeded, want error")
+	}
+}
+
+func TestChild(t *testing.T) {
+	resetGlobals()
+
+	p := MustNew("query", nil)
+	var wg sync.WaitGroup
+	for i := 0; i < 4; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < 100; j++ {
+				p.Child("getUser").LogDuration(time.Millisecond)
+			}
+		}()
+	}
+	wg.Wait()
+	c := p.Child("getUser")
+	if c.Name != "query/getUser" || c.Parent != p || len(p.Children) != 1 {
+		t.Fatalf("Child() = %q under %v with %v siblings, want a single query/getUser", c.Name, c.Parent, len(p.Children)-1)
+	}
+	if c.CalledTimes != 400 {
+		t.Errorf("CalledTimes = %v, want 400", c.CalledTimes)
+	}
+
+	MustNew("other/getUser", nil)
+	defer func() {
+		if recover() == nil {
+			t.Error("Child() of a name under a different parent didn't panic")
+		}
+	}()
+	MustNew("other", nil).Child("getUser")
+}
+
+func TestOnce(t *testing.T) {
+	resetGlobals()
+
+	tm := MustNew("once", nil)
+	var n int
+	for i := 0; i < 3; i++ {
+		tm.Once(func() { n++ })
+	}
+	if n != 1 || tm.CalledTimes != 1 {
+		t.Errorf("after 3x Once(): fn called %v times, CalledTimes = %v, want 1 and 1", n, tm.CalledTimes)
+	}
+}
+
+func TestCheckConsistency(t *testing.T) {
+	resetGlobals()
+
+	r := MustNew("root", nil)
+	c := MustNew("child", r)
+	if err := r.CheckConsistency(); err != nil {
+		t.Errorf("CheckConsistency() = %v, want nil", err)
+	}
+
+	stray := &Timer{Name: "stray", Parent: r}
+	if err := stray.CheckConsistency(); err == nil {
+		t.Error("CheckConsistency() of a timer unknown to its parent = nil, want error")
+	}
+
+	r.Children = append(r.Children, &Timer{Name: "orphan"})
+	if err := r.CheckConsistency(); err == nil {
+		t.Error("CheckConsistency() of a child without parent = nil, want error")
+	}
+	r.Children = r.Children[:1]
+
+	c.Children = append(c.Children, r)
+	if err := r.CheckConsistency(); err == nil {
+		t.Error("CheckConsistency() of a cycle = nil, want error")
+	}
+}
+
+func TestTableRulers(t *testing.T) {
+	resetGlobals()
+	defer func() { TableRulers = true }()
+
+	MustNew("root", nil).LogDuration(time.Second)
+	TableRulers = false
+	var b bytes.Buffer
+	ReportAll(&b)
+	want := "| Timer name | Total time | Nr. of calls | Average time/call |\n" +
+		"| root       |         1s |            1 |                1s |\n"
+	if b.String() != want {
+		t.Errorf("ReportAll() = %q, want %q", b.String(), want)
+	}
+}
+
+func TestReportEmptyMessage(t *testing.T) {
+	resetGlobals()
+	defer func() { ReportEmptyMessage = "" }()
+
+	tm := MustNew("idle", nil)
+	for _, msg := range []string{"", "no timing data collected"} {
+		ReportEmptyMessage = msg
+		want := ""
+		if msg != "" {
+			want = msg + "\n"
+		}
+		var b bytes.Buffer
+		ReportAll(&b)
+		if b.String() != want {
+			t.Errorf("ReportAll() = %q, want %q", b.String(), want)
+		}
+		b.Reset()
+		tm.Report(&b)
+		if b.String() != want {
+			t.Errorf("Report() = %q, want %q", b.String(), want)
+		}
+	}
+}
+
+func TestLogDurationWeighted(t *testing.T) {
+	resetGlobals()
+
+	tm := MustNew("batch", nil)
+	tm.LogDurationWeighted(time.Second, 500)
+	tm.LogDurationN(time.Second, 0)
+	if tm.TotalElapsed != 2*time.Second || tm.CalledTimes != 501 {
+		t.Errorf("TotalElapsed, CalledTimes = %v, %v, want 2s, 501", tm.TotalElapsed, tm.CalledTimes)
+	}
+	if tm.MaxElapsed != time.Second {
+		t.Errorf("MaxElapsed = %v, want 1s", tm.MaxElapsed)
+	}
+}
+
+func TestEnterExit(t *testing.T) {
+	resetGlobals()
+
+	clock := NewFakeClock(time.Unix(0, 0))
+	tm := MustNewWith("busy", nil, WithClock(clock))
+	var entered, exit, exited sync.WaitGroup
+	exit.Add(1)
+	for i := 0; i < 4; i++ {
+		entered.Add(1)
+		exited.Add(1)
+		go func() {
+			defer exited.Done()
+			tm.Enter()
+			entered.Done()
+			exit.Wait()
+			tm.Exit()
+		}()
+	}
+	entered.Wait()
+	clock.Advance(20 * time.Millisecond)
+	exit.Done()
+	exited.Wait()
+
+	// Four overlapping uses count as one busy period.
+	if tm.TotalElapsed != 20*time.Millisecond {
+		t.Errorf("TotalElapsed = %v, want 20ms", tm.TotalElapsed)
+	}
+	tm.Exit() // Unbalanced, ignored
+	if tm.CalledTimes != 4 {
+		t.Errorf("after unbalanced Exit(): CalledTimes = %v, want 4", tm.CalledTimes)
+	}
+}
+
+func TestValidateNames(t *testing.T) {
+	resetGlobals()
+
+	MustNew("db.query", nil)
+	MustNew("Bad_Name", nil)
+	MustNew("http", nil)
+	MustNew("also bad", nil)
+
+	errs := ValidateNames(regexp.MustCompile(`^[a-z]+(\.[a-z]+)*$`))
+	if len(errs) != 2 ||
+		!strings.Contains(errs[0].Error(), `"Bad_Name"`) ||
+		!strings.Contains(errs[1].Error(), `"also bad"`) {
+		t.Errorf("ValidateNames() = %v, want errors for Bad_Name and also bad", errs)
+	}
+}
+
+func TestLogSinceMulti(t *testing.T) {
+	resetGlobals()
+
+	a := MustNew("a", nil)
+	b := MustNew("b", nil)
+	c := MustNew("c", nil)
+	LogSinceMulti(time.Now().Add(-time.Second), a, b)
+	if a.CalledTimes != 1 || b.CalledTimes != 1 || a.TotalElapsed != b.TotalElapsed || a.TotalElapsed < time.Second {
+		t.Errorf("after LogSinceMulti(): a = %v/%v, b = %v/%v, want equal totals of at least 1s",
+			a.TotalElapsed, a.CalledTimes, b.TotalElapsed, b.CalledTimes)
+	}
+	if c.CalledTimes != 0 {
+		t.Errorf("after LogSinceMulti(): unrelated timer c was called %v times, want 0", c.CalledTimes)
+	}
+
+	// Each timer's own clock determines the end of the span.
+	clock := NewFakeClock(time.Unix(0, 0))
+	sim := MustNewWith("sim", nil, WithClock(clock))
+	start := sim.Now()
+	clock.Advance(2 * time.Second)
+	LogSinceMulti(start, sim)
+	if sim.TotalElapsed != 2*time.Second {
+		t.Errorf("after LogSinceMulti() with a FakeClock: TotalElapsed = %v, want 2s", sim.TotalElapsed)
+	}
+}
+
+func TestSetClock(t *testing.T) {
+	resetGlobals()
+
+	virtual := time.Unix(0, 0)
+	clock := ClockFunc(func() time.Time { return virtual })
+	sim := MustNewWith("sim", nil, WithClock(clock))
+	real := MustNew("real", nil)
+
+	start := sim.Now()
+	virtual = virtual.Add(time.Hour)
+	sim.LogSince(start)
+	if sim.TotalElapsed != time.Hour {
+		t.Errorf("TotalElapsed with virtual clock = %v, want 1h", sim.TotalElapsed)
+	}
+
+	real.LogSince(time.Now())
+	if real.TotalElapsed >= time.Hour {
+		t.Errorf("TotalElapsed with real clock = %v, want less than 1h", real.TotalElapsed)
+	}
+
+	sim.SetClock(nil)
+	if d := time.Since(sim.Now()); d < 0 || d > time.Minute {
+		t.Errorf("Now() after SetClock(nil) is %v away from the real time", d)
+	}
+}
+
+func TestReset(t *testing.T) {
+	resetGlobals()
+
+	r := MustNew("r", nil)
+	c := MustNew("c", r)
+	r.LogDuration(time.Second)
+	c.LogDuration(time.Second)
+
+	c.Reset()
+	if c.TotalElapsed != 0 || c.CalledTimes != 0 || r.CalledTimes != 1 {
+		t.Errorf("after c.Reset(): r = %v/%v, c = %v/%v, want only c cleared",
+			r.TotalElapsed, r.CalledTimes, c.TotalElapsed, c.CalledTimes)
+	}
+
+	c.LogDuration(time.Second)
+	ResetAll()
+	if r.CalledTimes != 0 || c.CalledTimes != 0 {
+		t.Errorf("after ResetAll(): r.CalledTimes = %v, c.CalledTimes = %v, want 0", r.CalledTimes, c.CalledTimes)
+	}
+	if c.Parent != r || len(r.Children) != 1 {
+		t.Error("ResetAll() changed the timer tree")
+	}
+}
+
+func TestResetRegistry(t *testing.T) {
+	resetGlobals()
+
+	old := MustNew("t", nil)
+	ResetRegistry()
+	if _, err := New("t", nil); err != nil {
+		t.Errorf("New() after ResetRegistry() = %v, want no error", err)
+	}
+
+	old.LogDuration(time.Second)
+	var b bytes.Buffer
+	ReportAll(&b)
+	if strings.Contains(b.String(), "1s") {
+		t.Errorf("ReportAll() = %q, want no orphaned timer", b.String())
+	}
+}
+
+func TestStart(t *testing.T) {
+	resetGlobals()
+
+	virtual := time.Unix(0, 0)
+	tm := MustNewWith("start", nil, WithClock(ClockFunc(func() time.Time { return virtual })))
+	stop := tm.Start()
+	virtual = virtual.Add(time.Second)
+	stop()
+	stop()
+	if tm.TotalElapsed != time.Second || tm.CalledTimes != 1 {
+		t.Errorf("after Start() and 2x stop: %v/%v, want 1s/1", tm.TotalElapsed, tm.CalledTimes)
+	}
+}
+
+func TestSortBy(t *testing.T) {
+	resetGlobals()
+	defer func() { OutputFormat, SortBy = Table, SortNone }()
+
+	r := MustNew("root", nil)
+	MustNew("b", r).LogDuration(time.Second)
+	c := MustNew("c", r)
+	c.LogDuration(time.Second)
+	c.LogDuration(time.Second)
+	MustNew("a", r).LogDuration(3 * time.Second)
+	r.LogDuration(10 * time.Second)
+
+	OutputFormat = CSV
+	for order, want := range map[SortOrder]string{
+		SortNone:      "bca",
+		SortTotalDesc: "acb",
+		SortCallsDesc: "cba",
+		SortNameAsc:   "abc",
+	} {
+		SortBy = order
+		var b bytes.Buffer
+		ReportAll(&b)
+		var got string
+		for _, line := range strings.Split(b.String(), "\n")[2:] {
+			if line != "" {
+				got += line[:1]
+			}
+		}
+		if got != want {
+			t.Errorf("SortBy %v: children %q, want %q", order, got, want)
+		}
+	}
+	if r.Children[0].Name != "b" {
+		t.Error("sorting changed the timer tree")
+	}
+}
+
+func TestReportAllAbove(t *testing.T) {
+	resetGlobals()
+	defer func() { OutputFormat = Table }()
+	OutputFormat = CSV
+
+	r := MustNew("root", nil)
+	mid := MustNew("mid", r)
+	MustNew("slow", mid).LogDuration(time.Second)
+	MustNew("fast", r).LogDuration(time.Microsecond)
+	mid.LogDuration(time.Millisecond)
+	r.LogDuration(time.Millisecond)
+
+	var b bytes.Buffer
+	ReportAllAbove(&b, 100*time.Millisecond)
+	want := "Timer;Total;Calls;Average\nroot;1ms;1;1ms\nmid;1ms;1;1ms\nslow;1s;1;1s\n"
+	if b.String() != want {
+		t.Errorf("ReportAllAbove() = %q, want %q", b.String(), want)
+	}
+
+	b.Reset()
+	mid.ReportAbove(&b, time.Hour)
+	if b.String() != "" {
+		t.Errorf("ReportAbove() = %q, want empty", b.String())
+	}
+}
+
+func TestNewWhileInactive(t *testing.T) {
+	resetGlobals()
+	defer func() { Active = true }()
+
+	Active = false
+	r := MustNew("root", nil)
+	c := MustNewPath("root/child")
+	if r == nil || c == nil || c.Parent != r {
+		t.Fatalf("timers created while inactive: %v, %v, want a root and its child", r, c)
+	}
+	c.LogDuration(time.Second)
+	if c.CalledTimes != 0 {
+		t.Errorf("CalledTimes while inactive = %v, want 0", c.CalledTimes)
+	}
+
+	Active = true
+	c.LogDuration(time.Second)
+	if c.CalledTimes != 1 {
+		t.Errorf("CalledTimes after activation = %v, want 1", c.CalledTimes)
+	}
+}
+
+func TestReportString(t *testing.T) {
+	resetGlobals()
+
+	r := MustNew("root", nil)
+	c := MustNew("child", r)
+	r.LogDuration(time.Second)
+	c.LogDuration(time.Second)
+
+	var b bytes.Buffer
+	ReportAll(&b)
+	if got := ReportAllString(); got != b.String() {
+		t.Errorf("ReportAllString() = %q, want %q", got, b.String())
+	}
+	b.Reset()
+	c.Report(&b)
+	if got := c.ReportString(); got != b.String() {
+		t.Errorf("ReportString() = %q, want %q", got, b.String())
+	}
+}
+
+// TestReportWhileLogging is meant to be run with -race.
+func TestReportWhileLogging(t *testing.T) {
+	resetGlobals()
+	defer func() { ShowPercentages, ReportInclusiveExclusive, SortBy = false, false, SortNone }()
+	ShowPercentages, ReportInclusiveExclusive, SortBy = true, true, SortTotalDesc
+
+	r := MustNew("root", nil)
+	c := MustNew("child", r)
+	c.TrackDistribution()
+
+	var wg sync.WaitGroup
+	stop := make(chan struct{})
+	for _, tm := range []*Timer{r, c, c} {
+		wg.Add(1)
+		go func(tm *Timer) {
+			defer wg.Done()
+			for {
+				select {
+				case <-stop:
+					return
+				default:
+					tm.LogSinceTagged(time.Now().Add(-time.Millisecond), "tag")
+				}
+			}
+		}(tm)
+	}
+	for i := 0; i < 50; i++ {
+		ReportAll(io.Discard)
+		ReportChanged(io.Discard)
+		r.Report(io.Discard)
+		r.Digest()
+		WriteChromeTrace(io.Discard)
+	}
+	close(stop)
+	wg.Wait()
+}
+
+// BenchmarkLogDuration measures the recording path, which takes the timer's lock. Compare with BenchmarkAtomicCounters, which only does the two additions.
+func BenchmarkLogDuration(b *testing.B) {
+	resetGlobals()
+	tm := MustNew("bench", nil)
+	b.RunParallel(func(pb *testing.PB) {
+		for pb.Next() {
+			tm.LogDuration(time.Microsecond)
+		}
+	})
+}
+
+// BenchmarkAtomicCounters is the lower bound of a lock-free recording path that only maintains the total and the number of calls.
+func BenchmarkAtomicCounters(b *testing.B) {
+	var total, calls atomic.Int64
+	b.RunParallel(func(pb *testing.PB) {
+		for pb.Next() {
+			total.Add(int64(time.Microsecond))
+			calls.Add(1)
+		}
+	})
+}
+
+func TestDurationFormat(t *testing.T) {
+	resetGlobals()
+	defer func(f func(time.Duration) string) { OutputFormat, DurationFormat = Table, f }(DurationFormat)
+
+	r := MustNew("root", nil)
+	MustNew("child", r).LogDuration(1500 * time.Microsecond)
+	r.LogDuration(12 * time.Millisecond)
+
+	DurationFormat = func(d time.Duration) string {
+		return fmt.Sprintf("%.2fms", float64(d)/float64(time.Millisecond))
+	}
+	OutputFormat = CSV
+	var b bytes.Buffer
+	ReportAll(&b)
+	want := "Timer;Total;Calls;Average\nroot;12.00ms;1;12.00ms\nchild;1.50ms;1;1.50ms\n"
+	if b.String() != want {
+		t.Errorf("ReportAll() = %q, want %q", b.String(), want)
+	}
+
+	OutputFormat = PlainText
+	b.Reset()
+	ReportAll(&b)
+	want = "root    total 12.00ms in 1 calls, avg 12.00ms\n  child total  1.50ms in 1 calls, avg  1.50ms\n"
+	if b.String() != want {
+		t.Errorf("ReportAll() = %q, want %q", b.String(), want)
+	}
+}
+
+func TestNewUnder(t *testing.T) {
+	resetGlobals()
+
+	r := MustNew("main", nil)
+	c := MustNewUnder("db", "main")
+	if c.Parent != r || len(r.Children) != 1 {
+		t.Errorf("NewUnder() created %v under %v, want a child of main", c.Name, c.Parent)
+	}
+	if _, err := NewUnder("x", "unknown"); err == nil {
+		t.Error("NewUnder() with an unknown parent succeeded, want error")
+	}
+}
+
+// TestConcurrentReports is meant to be run with -race.
+func TestConcurrentReports(t *testing.T) {
+	resetGlobals()
+
+	r := MustNew("root", nil)
+	c := MustNew("child", r)
+	var wg sync.WaitGroup
+	for i := 0; i < 4; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			for j := 0; j < 100; j++ {
+				switch i {
+				case 0:
+					ReportAll(io.Discard)
+				case 1:
+					r.Report(io.Discard)
+					c.Report(io.Discard)
+				case 2:
+					MustNew(fmt.Sprintf("new-%d", j), c).LogDuration(time.Millisecond)
+				case 3:
+					c.LogDuration(time.Millisecond)
+					r.LogDuration(time.Millisecond)
+				}
+			}
+		}(i)
+	}
+	wg.Wait()
+	if len(c.Children) != 100 {
+		t.Errorf("child has %v children, want 100", len(c.Children))
+	}
+}
+
+// TestConcurrentCreation is meant to be run with -race.
+func TestConcurrentCreation(t *testing.T) {
+	resetGlobals()
+
+	p := MustNew("parent", nil)
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			for j := 0; j < 50; j++ {
+				r := MustNew(fmt.Sprintf("root-%d-%d", i, j), nil)
+				MustNew(fmt.Sprintf("child-%d-%d", i, j), p).LogDuration(time.Millisecond)
+				MustNewUnder(fmt.Sprintf("grandchild-%d-%d", i, j), r.Name).LogDuration(time.Millisecond)
+			}
+		}(i)
+	}
+	wg.Wait()
+	if n := len(defaultRegistry.roots); n != 1+8*50 {
+		t.Errorf("%v roots, want %v", n, 1+8*50)
+	}
+	if n := len(p.Children); n != 8*50 {
+		t.Errorf("parent has %v children, want %v", n, 8*50)
+	}
+	if n := len(Names()); n != 1+3*8*50 {
+		t.Errorf("%v names, want %v", n, 1+3*8*50)
+	}
+}
+
+func TestSetActive(t *testing.T) {
+	resetGlobals()
+
+	r := MustNew("root", nil)
+	noisy := MustNew("noisy", r)
+	inner := MustNew("inner", noisy)
+	sibling := MustNew("sibling", r)
+
+	noisy.SetActive(false)
+	late := MustNew("late", noisy)
+	for _, tm := range []*Timer{r, noisy, inner, sibling, late} {
+		tm.LogDuration(time.Second)
+	}
+	for tm, want := range map[*Timer]int{r: 1, noisy: 0, inner: 0, sibling: 1, late: 0} {
+		if tm.CalledTimes != want {
+			t.Errorf("%v.CalledTimes = %v, want %v", tm.Name, tm.CalledTimes, want)
+		}
+	}
+
+	noisy.SetActive(true)
+	inner.LogDuration(time.Second)
+	if inner.CalledTimes != 1 {
+		t.Errorf("inner.CalledTimes after SetActive(true) = %v, want 1", inner.CalledTimes)
+	}
+}
+
+func TestWalk(t *testing.T) {
+	resetGlobals()
+
+	r := MustNew("root", nil)
+	a := MustNew("a", r)
+	MustNew("a1", a)
+	MustNew("b", r)
+	MustNew("other", nil)
+
+	a.LogDuration(time.Second)
+
+	var got []string
+	Walk(func(t *Timer, s TimerStats, depth int) bool {
+		got = append(got, fmt.Sprintf("%d%s:%v", depth, t.Name, s.TotalElapsed))
+		return t.Name != "a"
+	})
+	if want := "0root:0s 1a:1s 1b:0s 0other:0s"; strings.Join(got, " ") != want {
+		t.Errorf("Walk() visited %v, want %v", got, want)
+	}
+
+	got = nil
+	a.Walk(func(t *Timer, s TimerStats, depth int) bool {
+		got = append(got, fmt.Sprintf("%d%s", depth, t.Name))
+		return true
+	})
+	if want := "0a 1a1"; strings.Join(got, " ") != want {
+		t.Errorf("Timer.Walk() visited %v, want %v", got, want)
+	}
+
+	// The callback gets the timers themselves, and may call functions that lock the registry.
+	reg := NewRegistry()
+	q := reg.MustNew("query", nil)
+	var seen []*Timer
+	q.Walk(func(t *Timer, s TimerStats, depth int) bool {
+		seen = append(seen, t)
+		t.Snapshot()
+		t.Child("sub")
+		return true
+	})
+	if len(seen) != 1 || seen[0] != q {
+		t.Errorf("Timer.Walk() passed %v, want the timer itself", seen)
+	}
+	if _, ok := reg.Get("query/sub"); !ok {
+		t.Error("Child() in Timer.Walk() didn't create the child in the timer's registry")
+	}
+}
+
+func TestReportWriteError(t *testing.T) {
+	resetGlobals()
+
+	MustNew("root", nil).LogDuration(time.Second)
+	if err := ReportAll(failingWriter{}); err == nil {
+		t.Error("unbuffered ReportAll(failingWriter) = nil, want error")
+	}
+	if err := ReportAll(io.Discard); err != nil {
+		t.Errorf("ReportAll() = %v, want nil", err)
+	}
+}
+
+func TestSetParent(t *testing.T) {
+	resetGlobals()
+
+	a := MustNew("a", nil)
+	b := MustNew("b", nil)
+	c := MustNew("c", a)
+	if err := c.SetParent(b); err != nil {
+		t.Fatalf("SetParent() = %v, want nil", err)
+	}
+	if c.Parent != b || len(a.Children) != 0 || len(b.Children) != 1 {
+		t.Errorf("after SetParent(): c under %v, a has %v children, b has %v, want c under b", c.Parent.Name, len(a.Children), len(b.Children))
+	}
+	if err := b.SetParent(c); err == nil {
+		t.Error("SetParent() under a child succeeded, want error")
+	}
+	if err := c.SetParent(nil); err != nil || len(defaultRegistry.roots) != 3 {
+		t.Errorf("SetParent(nil) = %v with %v roots, want nil with 3 roots", err, len(defaultRegistry.roots))
+	}
+	if err := b.CheckConsistency(); err != nil {
+		t.Errorf("CheckConsistency() = %v", err)
+	}
+}
+
+func TestSetParentCycle(t *testing.T) {
+	resetGlobals()
+
+	gp := MustNew("grandparent", nil)
+	p := MustNew("parent", gp)
+	c := MustNew("child", p)
+	for _, test := range []struct {
+		t, parent *Timer
+	}{
+		{gp, c}, // Own grandparent
+		{gp, p},
+		{p, p}, // Own parent
+	} {
+		if err := test.t.SetParent(test.parent); err == nil {
+			t.Errorf("%v.SetParent(%v) succeeded, want error", test.t.Name, test.parent.Name)
+		}
+	}
+	if err := gp.CheckConsistency(); err != nil {
+		t.Errorf("CheckConsistency() after rejected SetParent() = %v", err)
+	}
+	ReportAll(io.Discard) // Must not hang
+}
+
+func TestReportMaxDepth(t *testing.T) {
+	resetGlobals()
+	defer func() { OutputFormat, ReportMaxDepth = Table, 0 }()
+
+	r := MustNew("root", nil)
+	a := MustNew("a", r)
+	a1 := MustNew("a1", a)
+	MustNew("a2", a1).LogDuration(time.Second)
+	MustNew("b", r).LogDuration(time.Second)
+	a.LogDuration(2 * time.Second)
+	r.LogDuration(4 * time.Second)
+
+	OutputFormat = CSV
+	ReportMaxDepth = 2
+	var b bytes.Buffer
+	ReportAll(&b)
+	want := "Timer;Total;Calls;Average;Hidden\nroot;4s;1;4s;\na;2s;1;2s;2\nb;1s;1;1s;\n"
+	if b.String() != want {
+		t.Errorf("ReportAll() = %q, want %q", b.String(), want)
+	}
+
+	ReportMaxDepth = 3
+	b.Reset()
+	ReportAll(&b)
+	want = "Timer;Total;Calls;Average;Hidden\nroot;4s;1;4s;\na;2s;1;2s;\na1;0s;0;;1\nb;1s;1;1s;\n"
+	if b.String() != want {
+		t.Errorf("ReportAll() = %q, want %q", b.String(), want)
+	}
+}
+
+func TestTime(t *testing.T) {
+	resetGlobals()
+
+	tm := MustNew("time", nil)
+	var called bool
+	tm.Time(func() { called = true })
+	if got := TimeVal(tm, func() int { return 42 }); got != 42 {
+		t.Errorf("TimeVal() = %v, want 42", got)
+	}
+	if !called || tm.CalledTimes != 2 {
+		t.Errorf("after Time() and TimeVal(): called = %v, CalledTimes = %v, want true and 2", called, tm.CalledTimes)
+	}
+}
+
+func TestActivateAfterCreation(t *testing.T) {
+	resetGlobals()
+	defer func() { Active, OutputFormat = true, Table }()
+
+	virtual := time.Unix(0, 0)
+	Active = false
+	r := MustNewWith("root", nil, WithClock(ClockFunc(func() time.Time { return virtual })))
+	c := MustNew("child", r)
+	r.LogDuration(time.Hour) // Not recorded
+	var b bytes.Buffer
+	ReportAll(&b)
+	if b.String() != "" {
+		t.Errorf("ReportAll() while inactive = %q, want empty", b.String())
+	}
+
+	Active = true
+	OutputFormat = CSV
+	func() {
+		defer r.LogSince(r.Now())
+		c.LogDuration(time.Millisecond)
+		virtual = virtual.Add(time.Second)
+	}()
+	ReportAll(&b)
+	want := "Timer;Total;Calls;Average\nroot;1s;1;1s\nchild;1ms;1;1ms\n"
+	if b.String() != want {
+		t.Errorf("ReportAll() after activation = %q, want %q", b.String(), want)
+	}
+}
+
+// BenchmarkLogSince measures the common usage of a deferred LogSince, including the call to time.Now() in the caller.
+func BenchmarkLogSince(b *testing.B) {
+	resetGlobals()
+	tm := MustNew("bench", nil)
+	for i := 0; i < b.N; i++ {
+		func() {
+			defer tm.LogSince(time.Now())
+		}()
+	}
+}
+
+// BenchmarkLogSinceInactive measures a deferred LogSince when Active is false, which still calls time.Now() in the caller.
+func BenchmarkLogSinceInactive(b *testing.B) {
+	resetGlobals()
+	tm := MustNew("bench", nil)
+	Active = false
+	defer func() { Active = true }()
+	for i := 0; i < b.N; i++ {
+		func() {
+			defer tm.LogSince(time.Now())
+		}()
+	}
+}
+
+// BenchmarkStartInactive measures a deferred Start when Active is false, which doesn't read the clock.
+func BenchmarkStartInactive(b *testing.B) {
+	resetGlobals()
+	tm := MustNew("bench", nil)
+	Active = false
+	defer func() { Active = true }()
+	for i := 0; i < b.N; i++ {
+		func() {
+			defer tm.Start()()
+		}()
+	}
+}
+
+func TestTreeIndents(t *testing.T) {
+	resetGlobals()
+	defer func() { OutputFormat, IndentUnit, TreeConnectors = Table, "  ", false }()
+
+	r := MustNew("root", nil)
+	a := MustNew("a", r)
+	MustNew("b", a).LogDuration(time.Second)
+	MustNew("c", a).LogDuration(time.Second)
+	MustNew("d", r).LogDuration(time.Second)
+	a.LogDuration(2 * time.Second)
+	r.LogDuration(3 * time.Second)
+
+	OutputFormat = PlainText
+	for _, test := range []struct {
+		indent     string
+		connectors bool
+		want       string
+	}{
+		{
+			indent: "....",
+			want: "root      total 3s in 1 calls, avg 3s\n" +
+				"....a     total 2s in 1 calls, avg 2s\n" +
+				"........b total 1s in 1 calls, avg 1s\n" +
+				"........c total 1s in 1 calls, avg 1s\n" +
+				"....d     total 1s in 1 calls, avg 1s\n",
+		},
+		{
+			indent:     "  ",
+			connectors: true,
+			want: "root  total 3s in 1 calls, avg 3s\n" +
+				"├─a   total 2s in 1 calls, avg 2s\n" +
+				"│ ├─b total 1s in 1 calls, avg 1s\n" +
+				"│ └─c total 1s in 1 calls, avg 1s\n" +
+				"└─d   total 1s in 1 calls, avg 1s\n",
+		},
+	} {
+		IndentUnit, TreeConnectors = test.indent, test.connectors
+		var b bytes.Buffer
+		ReportAll(&b)
+		if b.String() != test.want {
+			t.Errorf("ReportAll() with IndentUnit %q and TreeConnectors %v = %q, want %q", test.indent, test.connectors, b.String(), test.want)
+		}
+	}
+
+	// Rows that are cut off by ReportMaxRows still count as siblings.
+	defer func() { ReportMaxRows = 0 }()
+	ReportMaxRows = 3
+	var b bytes.Buffer
+	ReportAll(&b)
+	want := "root  total 3s in 1 calls, avg 3s\n" +
+		"├─a   total 2s in 1 calls, avg 2s\n" +
+		"│ ├─b total 1s in 1 calls, avg 1s\n" +
+		"… truncated, 2 more timers\n"
+	if b.String() != want {
+		t.Errorf("ReportAll() with ReportMaxRows 3 = %q, want %q", b.String(), want)
+	}
+}
+
+func TestClocks(t *testing.T) {
+	resetGlobals()
+	defer func() { DefaultClock = realClock{} }()
+
+	def := NewFakeClock(time.Unix(0, 0))
+	DefaultClock = def
+	tm := MustNew("default", nil)
+	start := tm.Now()
+	def.Advance(time.Second)
+	tm.LogSince(start)
+	if tm.TotalElapsed != time.Second {
+		t.Errorf("TotalElapsed with DefaultClock = %v, want 1s", tm.TotalElapsed)
+	}
+
+	reg := NewRegistry()
+	own := NewFakeClock(time.Unix(0, 0))
+	reg.SetClock(own)
+	rt := reg.MustNew("registry", nil)
+	start = rt.Now()
+	own.Advance(2 * time.Second)
+	def.Advance(time.Hour)
+	rt.LogSince(start)
+	if rt.TotalElapsed != 2*time.Second {
+		t.Errorf("TotalElapsed with Registry.SetClock() = %v, want 2s", rt.TotalElapsed)
+	}
+
+	reg.SetClock(nil)
+	if got := rt.Now(); !got.Equal(def.Now()) {
+		t.Errorf("Now() after Registry.SetClock(nil) = %v, want DefaultClock's %v", got, def.Now())
+	}
+}
+
+func TestZeroDurationCalls(t *testing.T) {
+	resetGlobals()
+	defer func() { OutputFormat = Table }()
+
+	r := MustNew("root", nil)
+	c := MustNew("cached", r)
+	MustNew("unused", r)
+	for i := 0; i < 1000; i++ {
+		c.LogDuration(0)
+	}
+
+	OutputFormat = CSV
+	var b bytes.Buffer
+	ReportAll(&b)
+	want := "Timer;Total;Calls;Average\nroot;0s;0;\ncached;0s;1000;0s\n"
+	if b.String() != want {
+		t.Errorf("ReportAll() = %q, want %q", b.String(), want)
+	}
+}
+
+func TestAverage(t *testing.T) {
+	resetGlobals()
+
+	tm := MustNew("avg", nil)
+	if got := tm.Average(); got != 0 {
+		t.Errorf("Average() without calls = %v, want 0", got)
+	}
+	tm.LogDuration(time.Second)
+	tm.LogDuration(2 * time.Second)
+	if got := tm.Average(); got != 1500*time.Millisecond {
+		t.Errorf("Average() = %v, want 1.5s", got)
+	}
+}
+
+func TestInFlight(t *testing.T) {
+	resetGlobals()
+	defer func() { OutputFormat, ShowPeakInFlight = Table, false }()
+
+	r := MustNew("root", nil)
+	c := MustNew("child", r)
+	var started, stop, stopped sync.WaitGroup
+	stop.Add(1)
+	for i := 0; i < 3; i++ {
+		started.Add(1)
+		stopped.Add(1)
+		go func() {
+			defer stopped.Done()
+			done := c.Start()
+			started.Done()
+			stop.Wait()
+			done()
+		}()
+	}
+	started.Wait()
+	c.Enter()
+	if got := c.InFlight(); got != 4 {
+		t.Errorf("InFlight() = %v, want 4", got)
+	}
+	c.Exit()
+	stop.Done()
+	stopped.Wait()
+	stopR := r.Start()
+	stopR()
+	stopR() // Ignored
+	if got, peak := c.InFlight(), c.PeakInFlight(); got != 0 || peak != 4 {
+		t.Errorf("InFlight(), PeakInFlight() = %v, %v, want 0, 4", got, peak)
+	}
+
+	OutputFormat = PlainText
+	ShowPeakInFlight = true
+	if got := ReportAllString(); !strings.Contains(got, "peak 4 in flight") || !strings.Contains(got, "peak 1 in flight") {
+		t.Errorf("ReportAll() = %q, want the peaks", got)
+	}
+}
+
+func TestReportRoots(t *testing.T) {
+	resetGlobals()
+	defer func() { OutputFormat, SortBy = Table, SortNone }()
+
+	a := MustNew("a", nil)
+	b := MustNew("b", nil)
+	MustNew("c", nil).LogDuration(3 * time.Second)
+	MustNew("b1", b).LogDuration(time.Second)
+	MustNew("b2", b).LogDuration(2 * time.Second)
+	a.LogDuration(time.Second)
+	b.LogDuration(4 * time.Second)
+
+	OutputFormat = PlainText
+	SortBy = SortTotalDesc
+	var buf bytes.Buffer
+	if err := ReportRoots(&buf, a, b); err != nil {
+		t.Fatalf("ReportRoots() = %v", err)
+	}
+	want := "a    total 1s in 1 calls, avg 1s\n" +
+		"b    total 4s in 1 calls, avg 4s\n" +
+		"  b2 total 2s in 1 calls, avg 2s\n" +
+		"  b1 total 1s in 1 calls, avg 1s\n"
+	if buf.String() != want {
+		t.Errorf("ReportRoots() = %q, want %q", buf.String(), want)
+	}
+
+	if err := ReportRoots(&buf, a, NewRegistry().MustNew("other", nil)); err == nil {
+		t.Error("ReportRoots() of timers in different registries = nil, want error")
+	}
+}
+
+func TestReportHeader(t *testing.T) {
+	resetGlobals()
+	defer func() { OutputFormat, ReportHeader, DefaultClock = Table, "", realClock{} }()
+	DefaultClock = NewFakeClock(time.Date(2024, 3, 1, 12, 0, 0, 0, time.UTC))
+
+	MustNew("root", nil).LogDuration(time.Second)
+	ReportHeader = "calltimer report"
+	for format, want := range map[Format]string{
+		PlainText: "=== calltimer report 2024-03-01T12:00:00Z ===\nroot total 1s in 1 calls, avg 1s\n",
+		CSV:       "Timer;Total;Calls;Average\nroot;1s;1;1s\n",
+	} {
+		OutputFormat = format
+		if got := ReportAllString(); got != want {
+			t.Errorf("ReportAll() in format %v = %q, want %q", format, got, want)
+		}
+	}
+}
+
+func TestNegativeDuration(t *testing.T) {
+	resetGlobals()
+
+	tm := MustNew("skewed", nil)
+	tm.LogDuration(time.Second)
+	tm.LogSince(time.Now().Add(time.Hour))
+	tm.LogDuration(-time.Second)
+	if tm.TotalElapsed != time.Second || tm.CalledTimes != 3 {
+		t.Errorf("TotalElapsed, CalledTimes = %v, %v, want 1s, 3", tm.TotalElapsed, tm.CalledTimes)
+	}
+	if got := tm.Clamped(); got != 2 {
+		t.Errorf("Clamped() = %v, want 2", got)
+	}
+	tm.Reset()
+	if got := tm.Clamped(); got != 0 {
+		t.Errorf("Clamped() after Reset() = %v, want 0", got)
+	}
+}
+
+func TestReportSubtrees(t *testing.T) {
+	resetGlobals()
+	defer func() { OutputFormat = Table }()
+
+	r := MustNew("root", nil)
+	short := MustNew("a", r)
+	long := MustNew("much-longer-branch", r)
+	MustNew("leaf", long).LogDuration(time.Second)
+	short.LogDuration(10 * time.Second)
+	long.LogDuration(2 * time.Second)
+
+	OutputFormat = PlainText
+	var b bytes.Buffer
+	if err := ReportSubtrees(&b, short, long); err != nil {
+		t.Fatalf("ReportSubtrees() = %v", err)
+	}
+	want := "a                  total 10s in 1 calls, avg 10s\n" +
+		"much-longer-branch total  2s in 1 calls, avg  2s\n" +
+		"  leaf             total  1s in 1 calls, avg  1s\n"
+	if b.String() != want {
+		t.Errorf("ReportSubtrees() = %q, want %q", b.String(), want)
+	}
+}
+
+func TestColumnWidths(t *testing.T) {
+	resetGlobals()
+
+	if l, tot, c, a := ColumnWidths(); l+tot+c+a != 0 {
+		t.Errorf("ColumnWidths() without activity = %v, %v, %v, %v, want zeros", l, tot, c, a)
+	}
+
+	r := MustNew("root", nil)
+	long := MustNew("much-longer-branch", r)
+	MustNew("leaf", long).LogDuration(time.Second)
+	long.LogDuration(2 * time.Second)
+	for i := 0; i < 10; i++ {
+		r.LogDuration(time.Second)
+	}
+
+	// "  much-longer-branch", "10s", "10" and "2s"
+	if l, tot, c, a := ColumnWidths(); l != 20 || tot != 3 || c != 2 || a != 2 {
+		t.Errorf("ColumnWidths() = %v, %v, %v, %v, want 20, 3, 2, 2", l, tot, c, a)
+	}
+	// "much-longer-branch", "2s", "1" and "2s"
+	if l, tot, c, a := ColumnWidths(long); l != 18 || tot != 2 || c != 1 || a != 2 {
+		t.Errorf("ColumnWidths(long) = %v, %v, %v, %v, want 18, 2, 1, 2", l, tot, c, a)
+	}
+}
@@ -0,0 +1,68 @@
+package calltimer
+
+import "time"
+
+/*
+TimerSnapshot is a point-in-time view of a timer and its descendants, see Snapshot().
+*/
+type TimerSnapshot struct {
+	Name         string          // Timer name
+	TotalElapsed time.Duration   // Total duration
+	CalledTimes  int             // Number of invocations
+	MaxElapsed   time.Duration   // Duration of the slowest invocation
+	LastElapsed  time.Duration   // Duration of the most recent invocation
+	Children     []TimerSnapshot // Snapshots of the children
+}
+
+/*
+TimerStats is a consistent copy of the statistics of a single timer, which Walk() passes next to the timer.
+*/
+type TimerStats struct {
+	TotalElapsed time.Duration // Total duration
+	CalledTimes  int           // Number of invocations
+	MaxElapsed   time.Duration // Duration of the slowest invocation
+	LastElapsed  time.Duration // Duration of the most recent invocation
+}
+
+/*
+Average returns the average duration per call, or 0 when there were no calls.
+*/
+func (s TimerStats) Average() time.Duration {
+	return average(s.TotalElapsed, s.CalledTimes)
+}
+
+// stats returns a copy of the timer's statistics, taken under the timer's lock.
+func (t *Timer) stats() TimerStats {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	return TimerStats{TotalElapsed: t.TotalElapsed, CalledTimes: t.CalledTimes, MaxElapsed: t.MaxElapsed, LastElapsed: t.LastElapsed}
+}
+
+/*
+Snapshot returns an immutable view of the timer and its descendants. The statistics of each timer are read under its lock, so they are consistent with each other; reading the fields of a Timer one by one may interleave with concurrent logging, giving e.g. an average that never was.
+*/
+func (t *Timer) Snapshot() TimerSnapshot {
+	reg := t.registry()
+	reg.mu.Lock()
+	defer reg.mu.Unlock()
+
+	return t.snapshot()
+}
+
+// snapshot implements Snapshot(). The registry must be locked.
+func (t *Timer) snapshot() TimerSnapshot {
+	s := t.copyStats()
+	ts := TimerSnapshot{
+		Name:         s.Name,
+		TotalElapsed: s.TotalElapsed,
+		CalledTimes:  s.CalledTimes,
+		MaxElapsed:   s.MaxElapsed,
+		LastElapsed:  s.LastElapsed,
+		Children:     make([]TimerSnapshot, 0, len(t.Children)),
+	}
+	for _, c := range t.Children {
+		ts.Children = append(ts.Children, c.snapshot())
+	}
+	return ts
+}
@@ -0,0 +1,25 @@
+package calltimer
+
+import (
+	"testing"
+	"time"
+)
+
+func TestSnapshot(t *testing.T) {
+	resetGlobals()
+
+	r := MustNew("root", nil)
+	c := MustNew("child", r)
+	r.LogDuration(3 * time.Second)
+	c.LogDuration(time.Second)
+
+	s := r.Snapshot()
+	r.LogDuration(time.Second)
+	c.LogDuration(time.Second)
+	if s.Name != "root" || s.TotalElapsed != 3*time.Second || s.CalledTimes != 1 {
+		t.Errorf("Snapshot() = %+v, want root with 3s in 1 call", s)
+	}
+	if len(s.Children) != 1 || s.Children[0].Name != "child" || s.Children[0].CalledTimes != 1 {
+		t.Errorf("Snapshot().Children = %+v, want child with 1 call", s.Children)
+	}
+}
@@ -0,0 +1,67 @@
+package calltimer
+
+import (
+	"encoding/json"
+	"io"
+	"time"
+)
+
+// traceEvent is a Complete event of the Chrome trace event format.
+type traceEvent struct {
+	Name string         `json:"name"`
+	Ph   string         `json:"ph"`
+	Ts   float64        `json:"ts"`  // Start in microseconds
+	Dur  float64        `json:"dur"` // Duration in microseconds
+	Pid  int            `json:"pid"`
+	Tid  int            `json:"tid"`
+	Args map[string]int `json:"args"`
+}
+
+/*
+WriteChromeTrace writes all timers with activity as a trace in the Chrome trace event format, which can be loaded into chrome://tracing or https://ui.perfetto.dev to visualize the timer tree as a flame graph. Since timers only hold totals, each timer is a single span with its TotalElapsed as duration. The roots are laid out one after another, and the children of a timer one after another from the start of their parent. The number of calls is available as an argument of each span.
+*/
+func WriteChromeTrace(wr io.Writer) error {
+	return defaultRegistry.WriteChromeTrace(wr)
+}
+
+/*
+WriteChromeTrace is like the package-level WriteChromeTrace(), but writes the timers of this registry.
+*/
+func (r *Registry) WriteChromeTrace(wr io.Writer) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	events := []traceEvent{}
+	rows := reportRows(r.roots, (*Timer).hasOwnActivity)
+	starts := spanStarts(rows)
+	for i, row := range rows {
+		events = append(events, traceEvent{
+			Name: row.Name,
+			Ph:   "X",
+			Ts:   microseconds(starts[i]),
+			Dur:  microseconds(row.Total),
+			Pid:  1,
+			Tid:  1,
+			Args: map[string]int{"calls": row.Calls},
+		})
+	}
+	return json.NewEncoder(wr).Encode(struct {
+		TraceEvents []traceEvent `json:"traceEvents"`
+	}{events})
+}
+
+// spanStarts lays out the rows as spans, and returns the start of each span relative to the start of the first. The roots are laid out one after another, and the children of a timer one after another from the start of their parent.
+func spanStarts(rows []ReportRow) []time.Duration {
+	starts := make([]time.Duration, len(rows))
+	next := []time.Duration{0} // Per depth, the start of the next span
+	for i, r := range rows {
+		starts[i] = next[r.Depth]
+		next = append(next[:r.Depth], starts[i]+r.Total, starts[i])
+	}
+	return starts
+}
+
+// microseconds returns d in microseconds, the time unit of traces.
+func microseconds(d time.Duration) float64 {
+	return float64(d) / float64(time.Microsecond)
+}
@@ -0,0 +1,49 @@
+package calltimer
+
+import (
+	"bytes"
+	"encoding/json"
+	"testing"
+	"time"
+)
+
+func TestWriteChromeTrace(t *testing.T) {
+	resetGlobals()
+
+	r1 := MustNew("r1", nil)
+	a := MustNew("a", r1)
+	b := MustNew("b", r1)
+	MustNew("idle", r1)
+	r2 := MustNew("r2", nil)
+	r1.LogDuration(10 * time.Millisecond)
+	a.LogDuration(3 * time.Millisecond)
+	b.LogDuration(2 * time.Millisecond)
+	r2.LogDuration(time.Millisecond)
+
+	var buf bytes.Buffer
+	if err := WriteChromeTrace(&buf); err != nil {
+		t.Fatalf("WriteChromeTrace() = %v", err)
+	}
+	var got struct{ TraceEvents []traceEvent }
+	if err := json.Unmarshal(buf.Bytes(), &got); err != nil {
+		t.Fatalf("WriteChromeTrace() = %q: %v", buf.String(), err)
+	}
+	want := []struct {
+		name    string
+		ts, dur float64
+	}{
+		{"r1", 0, 10000},
+		{"a", 0, 3000},
+		{"b", 3000, 2000},
+		{"r2", 10000, 1000},
+	}
+	if len(got.TraceEvents) != len(want) {
+		t.Fatalf("WriteChromeTrace() = %+v, want %v events", got.TraceEvents, len(want))
+	}
+	for i, w := range want {
+		e := got.TraceEvents[i]
+		if e.Name != w.name || e.Ph != "X" || e.Ts != w.ts || e.Dur != w.dur || e.Args["calls"] != 1 {
+			t.Errorf("event %d = %+v, want %v at %v for %v", i, e, w.name, w.ts, w.dur)
+		}
+	}
+}
@@ -0,0 +1,13 @@
+package calltimer
+
+import "time"
+
+// started is the time at which the package was initialized.
+var started = time.Now()
+
+/*
+Uptime returns the time since the package was initialized, which is a close approximation of the process' uptime. It serves as a baseline of the total program time, even when main() isn't wrapped in a timer. See also ShowUptimeShare.
+*/
+func Uptime() time.Duration {
+	return time.Since(started)
+}
@@ -0,0 +1,62 @@
+package calltimer
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestUptime(t *testing.T) {
+	resetGlobals()
+	defer func() { ShowUptimeShare = false }()
+
+	if Uptime() <= 0 {
+		t.Errorf("Uptime() = %v, want > 0", Uptime())
+	}
+
+	r := MustNew("root", nil)
+	MustNew("child", r).LogDuration(time.Nanosecond)
+	r.LogDuration(time.Nanosecond)
+
+	ShowUptimeShare = true
+	var b bytes.Buffer
+	ReportAll(&b)
+	lines := strings.Split(b.String(), "\n")
+	if !strings.HasSuffix(lines[1], "| % of uptime |") {
+		t.Errorf("header %q lacks uptime column", lines[1])
+	}
+	if !strings.HasSuffix(lines[3], "% |") {
+		t.Errorf("root row %q lacks uptime share", lines[3])
+	}
+	if strings.HasSuffix(lines[4], "% |") {
+		t.Errorf("child row %q has uptime share", lines[4])
+	}
+}
+
+func TestReportAllWithWallClock(t *testing.T) {
+	resetGlobals()
+
+	r := MustNew("root", nil)
+	MustNew("child", r).LogDuration(time.Second)
+	r.LogDuration(time.Second)
+
+	var b bytes.Buffer
+	ReportAllWithWallClock(&b, 4*time.Second)
+	lines := strings.Split(b.String(), "\n")
+	if !strings.HasSuffix(lines[1], "| % of wall |") {
+		t.Errorf("header %q lacks wall column", lines[1])
+	}
+	if !strings.HasSuffix(lines[3], "|     25.0% |") {
+		t.Errorf("root row %q lacks wall share of 25%%", lines[3])
+	}
+	if strings.HasSuffix(lines[4], "% |") {
+		t.Errorf("child row %q has wall share", lines[4])
+	}
+
+	b.Reset()
+	ReportAll(&b)
+	if strings.Contains(b.String(), "% of wall") {
+		t.Errorf("ReportAll() = %q, want no wall column", b.String())
+	}
+}
@@ -0,0 +1,90 @@
+package calltimer
+
+import (
+	"fmt"
+	"time"
+)
+
+/*
+StartWindow marks the start of a wall-clock window of the timer, typically the start of a load test. Reports then show the throughput of the timer in calls per second: the number of calls divided by the duration of the window. The window lasts until EndWindow() is called; while it's open, it lasts until the time of the report. Calling StartWindow again starts a new window.
+*/
+func (t *Timer) StartWindow() {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	t.windowStart = t.now()
+	t.windowEnd = time.Time{}
+}
+
+/*
+EndWindow marks the end of the wall-clock window that was started using StartWindow().
+*/
+func (t *Timer) EndWindow() {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if !t.windowStart.IsZero() {
+		t.windowEnd = t.now()
+	}
+}
+
+/*
+Finalize freezes the statistics of the timer that depend on the time of the report at the instant at, so that reports are reproducible. The only such statistic is the throughput of an open window (see StartWindow()), which lasts until the time of the report; Finalize ends it at at, like EndWindow() does at the current time. A window that was already ended keeps its end. Together with a FakeClock, this makes throughput exact in tests:
+
+	clock := calltimer.NewFakeClock(start)
+	loadTimer := calltimer.MustNewWith("load", nil, calltimer.WithClock(clock))
+	loadTimer.StartWindow()
+	...
+	loadTimer.Finalize(clock.Now())
+
+The other statistics only change when calls are logged, so Finalize doesn't affect them.
+*/
+func (t *Timer) Finalize(at time.Time) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if !t.windowStart.IsZero() && t.windowEnd.IsZero() {
+		t.windowEnd = at
+	}
+}
+
+/*
+CallsPerSecond returns the number of calls divided by the duration of the timer's window, see StartWindow(). It returns 0 when the timer has no window.
+*/
+func (t *Timer) CallsPerSecond() float64 {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	return t.callsPerSecond()
+}
+
+// callsPerSecond implements CallsPerSecond(). The timer must be locked, or be a copy.
+func (t *Timer) callsPerSecond() float64 {
+	if t.windowStart.IsZero() {
+		return 0
+	}
+	end := t.windowEnd
+	if end.IsZero() {
+		end = t.now()
+	}
+	window := end.Sub(t.windowStart)
+	if window <= 0 {
+		return 0
+	}
+	return float64(t.CalledTimes) / window.Seconds()
+}
+
+// throughputColumn returns the column that shows calls/s.
+func throughputColumn() column {
+	return column{
+		label:  "calls/s",
+		plain:  "%s calls/s",
+		legend: "calls per second during the timer's window, see StartWindow()",
+		value: func(r ReportRow) string {
+			if r.Calls == 0 || r.stats.windowStart.IsZero() {
+				return ""
+			}
+			return fmt.Sprintf("%.2f", r.stats.callsPerSecond())
+		},
+	}
+}
@@ -0,0 +1,64 @@
+package calltimer
+
+import (
+	"bytes"
+	"testing"
+	"time"
+)
+
+func TestWindow(t *testing.T) {
+	resetGlobals()
+	defer func() { OutputFormat = Table }()
+
+	virtual := time.Unix(0, 0)
+	clock := ClockFunc(func() time.Time { return virtual })
+	r := MustNewWith("root", nil, WithClock(clock))
+	c := MustNewWith("child", r, WithClock(clock))
+	MustNew("idle", r).StartWindow()
+
+	r.StartWindow()
+	for i := 0; i < 10; i++ {
+		r.LogDuration(time.Millisecond)
+	}
+	virtual = virtual.Add(4 * time.Second)
+	if got := r.CallsPerSecond(); got != 2.5 {
+		t.Errorf("CallsPerSecond() of an open window = %v, want 2.5", got)
+	}
+	r.EndWindow()
+	virtual = virtual.Add(time.Hour)
+	c.LogDuration(time.Millisecond)
+
+	OutputFormat = CSV
+	var b bytes.Buffer
+	ReportAll(&b)
+	want := "Timer;Total;Calls;Average;calls/s\nroot;10ms;10;1ms;2.50\nchild;1ms;1;1ms;\n"
+	if b.String() != want {
+		t.Errorf("ReportAll() = %q, want %q", b.String(), want)
+	}
+}
+
+func TestFinalize(t *testing.T) {
+	resetGlobals()
+
+	clock := NewFakeClock(time.Unix(0, 0))
+	tm := MustNewWith("load", nil, WithClock(clock))
+	tm.Finalize(clock.Now())
+	if got := tm.CallsPerSecond(); got != 0 {
+		t.Errorf("CallsPerSecond() without a window = %v, want 0", got)
+	}
+
+	tm.StartWindow()
+	for i := 0; i < 10; i++ {
+		tm.LogDuration(time.Millisecond)
+	}
+	clock.Advance(2 * time.Second)
+	tm.Finalize(clock.Now())
+	clock.Advance(time.Hour)
+	if got := tm.CallsPerSecond(); got != 5 {
+		t.Errorf("CallsPerSecond() after Finalize() = %v, want 5", got)
+	}
+	tm.Finalize(clock.Now())
+	if got := tm.CallsPerSecond(); got != 5 {
+		t.Errorf("CallsPerSecond() after a second Finalize() = %v, want 5", got)
+	}
+}